@@ -0,0 +1,126 @@
+package insteon
+
+import (
+	"sort"
+	"time"
+)
+
+// Alarm is an alarm condition, like smoke or a water leak, reported by a
+// device.
+//
+// Alarms are latched: they remain once their condition ends, until they
+// are cleared.
+type Alarm struct {
+	// Type is the attribute that reports the condition, like smoke or wet.
+	Type string `json:"type"`
+
+	// Active tells whether the condition is still reported.
+	Active bool `json:"active"`
+
+	// Acknowledged tells whether the alarm was acknowledged since it was
+	// last raised.
+	Acknowledged bool `json:"acknowledged"`
+
+	Since time.Time `json:"since"`
+}
+
+// latchAlarms updates the alarms of a device from the conditions reported
+// by an event and returns the conditions that became active.
+func (d *Devices) latchAlarms(identity ID, conditions map[string]interface{}) (raised []string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	alarms := d.alarms[identity]
+
+	for condition, value := range conditions {
+		active, ok := value.(bool)
+
+		if !ok {
+			continue
+		}
+
+		found := false
+
+		for i := range alarms {
+			if alarms[i].Type != condition {
+				continue
+			}
+
+			found = true
+
+			if active && !alarms[i].Active {
+				alarms[i] = Alarm{Type: condition, Active: true, Since: time.Now().UTC()}
+				raised = append(raised, condition)
+			} else {
+				alarms[i].Active = active
+			}
+		}
+
+		if !found && active {
+			alarms = append(alarms, Alarm{Type: condition, Active: true, Since: time.Now().UTC()})
+			raised = append(raised, condition)
+		}
+	}
+
+	sort.Slice(alarms, func(i, j int) bool { return alarms[i].Type < alarms[j].Type })
+	sort.Strings(raised)
+
+	d.alarms[identity] = alarms
+
+	return raised
+}
+
+// Alarms returns the latched alarms of the devices that have any.
+func (d *Devices) Alarms() map[ID][]Alarm {
+	d.init()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	result := make(map[ID][]Alarm, len(d.alarms))
+
+	for identity, alarms := range d.alarms {
+		if len(alarms) > 0 {
+			result[identity] = append([]Alarm(nil), alarms...)
+		}
+	}
+
+	return result
+}
+
+// Alarms returns the latched alarms of the device.
+func (d *BaseDevice) Alarms() []Alarm {
+	d.devices.lock.Lock()
+	defer d.devices.lock.Unlock()
+
+	return append([]Alarm{}, d.devices.alarms[d.id]...)
+}
+
+// AcknowledgeAlarms acknowledges the latched alarms of the device.
+func (d *BaseDevice) AcknowledgeAlarms() {
+	d.devices.lock.Lock()
+	defer d.devices.lock.Unlock()
+
+	for i := range d.devices.alarms[d.id] {
+		d.devices.alarms[d.id][i].Acknowledged = true
+	}
+}
+
+// ClearAlarms clears the latched alarms of the device whose conditions
+// ended and returns the remaining ones, which are still active.
+func (d *BaseDevice) ClearAlarms() []Alarm {
+	d.devices.lock.Lock()
+	defer d.devices.lock.Unlock()
+
+	var alarms []Alarm
+
+	for _, alarm := range d.devices.alarms[d.id] {
+		if alarm.Active {
+			alarms = append(alarms, alarm)
+		}
+	}
+
+	d.devices.alarms[d.id] = alarms
+
+	return append([]Alarm{}, alarms...)
+}
@@ -26,6 +26,21 @@ var (
 	holiday                 MainCategory = 0x16
 	unassigned              MainCategory = 0xFF
 
+	// generalizedControllers subcategories.
+	remoteLinc2440          SubCategory = 0x06
+	remoteLinc2Keypad4Scene SubCategory = 0x10
+	remoteLinc2Switch       SubCategory = 0x11
+	remoteLinc2Keypad8Scene SubCategory = 0x12
+	miniRemote4Scene        SubCategory = 0x14
+	miniRemoteSwitch        SubCategory = 0x15
+	miniRemote8Scene        SubCategory = 0x16
+	miniRemote4Scene869MHz  SubCategory = 0x17
+	miniRemoteSwitch869MHz  SubCategory = 0x18
+	miniRemote8Scene869MHz  SubCategory = 0x19
+	miniRemote4Scene921MHz  SubCategory = 0x1A
+	miniRemoteSwitch921MHz  SubCategory = 0x1B
+	miniRemote8Scene921MHz  SubCategory = 0x1C
+
 	// networkBridges subcategories.
 	powerlincSerial               SubCategory = 0x01
 	powerlincUsb                  SubCategory = 0x02
@@ -34,6 +49,28 @@ var (
 	smartlabsPowerLineModemSerial SubCategory = 0x05
 	powerlincDualBandSerial       SubCategory = 0x11
 	powerlincDualBandUsb          SubCategory = 0x15
+
+	// dimmableLightingControl subcategories.
+	keypadLincDimmer2486D      SubCategory = 0x09
+	keypadLincDimmer2486DWH8   SubCategory = 0x0C
+	keypadLincDimmer2486DWH6   SubCategory = 0x1B
+	keypadLincDimmer2334232    SubCategory = 0x1C
+	fanLinc                    SubCategory = 0x2E
+	keypadLincDimmer23342Eight SubCategory = 0x41
+	keypadLincDimmer23342Six   SubCategory = 0x42
+
+	// switchedLightingControl subcategories.
+	keypadLincRelay2486SWH8 SubCategory = 0x05
+	keypadLincRelay2487S    SubCategory = 0x0F
+	outletLinc2663          SubCategory = 0x39
+
+	// securityHealthSafety subcategories.
+	motionSensor2842     SubCategory = 0x01
+	openCloseSensor2843  SubCategory = 0x02
+	hiddenDoorSensor2845 SubCategory = 0x07
+	leakSensor2852       SubCategory = 0x08
+	smokeBridge2982      SubCategory = 0x0A
+	motionSensor2844     SubCategory = 0x16
 )
 
 // MainCategory represents a main category.
@@ -44,8 +81,8 @@ type SubCategory uint8
 
 // Category represents a category.
 type Category struct {
-	MainCategory `json:"main"`
-	SubCategory  `json:"sub"`
+	MainCategory `json:"main" yaml:"main"`
+	SubCategory  `json:"sub" yaml:"sub"`
 }
 
 // UnmarshalBinary -
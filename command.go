@@ -0,0 +1,40 @@
+package insteon
+
+// Command is a raw command sent to a device.
+type Command struct {
+	CommandBytes [2]byte `json:"command_bytes"`
+
+	// UserData, if set, makes the command an extended one.
+	UserData *[14]byte `json:"user_data,omitempty"`
+
+	// ExtendedResponse indicates that the device answers the command with an
+	// extended message, after its acknowledgment.
+	ExtendedResponse bool `json:"extended_response,omitempty"`
+}
+
+// CommandResponse is the response of a device to a command.
+type CommandResponse struct {
+	Ack      Message  `json:"ack"`
+	Extended *Message `json:"extended,omitempty"`
+}
+
+// NewCommand returns a standard command.
+func NewCommand(commandBytes [2]byte) Command {
+	return Command{CommandBytes: commandBytes}
+}
+
+// NewExtendedCommand returns an extended command.
+func NewExtendedCommand(commandBytes [2]byte, userData [14]byte) Command {
+	return Command{
+		CommandBytes: commandBytes,
+		UserData:     &userData,
+	}
+}
+
+func (c Command) asMessage(target ID) *Message {
+	if c.UserData != nil {
+		return newExtendedMessage(target, c.CommandBytes, *c.UserData)
+	}
+
+	return newMessage(target, c.CommandBytes)
+}
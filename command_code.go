@@ -48,3 +48,7 @@ func isIncomingCommandCode(commandCode CommandCode) bool {
 func isOutgoingCommandCode(commandCode CommandCode) bool {
 	return commandCode >= cmdGetIMInfo
 }
+
+func isPowerLineCommandCode(commandCode CommandCode) bool {
+	return commandCode == cmdSendAllLink || commandCode == cmdSendStandardOrExtendedMessage
+}
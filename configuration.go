@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -14,6 +15,7 @@ import (
 type Configuration struct {
 	Devices []ConfigurationDevice `yaml:"devices"`
 	Hubitat HubitatConfiguration  `yaml:"hubitat"`
+	Server  ServerConfiguration   `yaml:"server"`
 }
 
 // ErrNoSuchDevice is returned whenever a lookup on a given device alias
@@ -43,6 +45,18 @@ func (c *Configuration) GetDevice(id ID) (*ConfigurationDevice, error) {
 	return nil, ErrNoSuchDevice{ID: id}
 }
 
+// GetDevices finds all the devices with an id, as OutletLinc outlets share
+// theirs.
+func (c *Configuration) GetDevices(id ID) (devices []ConfigurationDevice) {
+	for _, device := range c.Devices {
+		if device.ID == id {
+			devices = append(devices, device)
+		}
+	}
+
+	return devices
+}
+
 // LookupDevice finds a device from its alias.
 func (c *Configuration) LookupDevice(alias string) (*ConfigurationDevice, error) {
 	for _, device := range c.Devices {
@@ -62,6 +76,48 @@ type ConfigurationDevice struct {
 	Group           string `yaml:"group,omitempty" json:"-"`
 	MirrorDeviceIDs []ID   `yaml:"mirror_devices" json:"-"`
 	ControllerIDs   []ID   `yaml:"controllers" json:"-"`
+
+	// Category, if set, saves a lookup and is required for devices that
+	// sleep.
+	Category *Category `yaml:"category,omitempty" json:"category,omitempty"`
+
+	// Buttons are the sub-devices of keypads.
+	Buttons []ConfigurationButton `yaml:"buttons,omitempty" json:"buttons,omitempty"`
+
+	// Outlet, if set, makes the device a logical device for one of the
+	// outlets of an OutletLinc, which shares its id with the other one: 1
+	// for the top outlet and 2 for the bottom outlet.
+	Outlet int `yaml:"outlet,omitempty" json:"outlet,omitempty"`
+
+	// TravelTime is the time that garage doors and window coverings take to
+	// fully open or close.
+	TravelTime time.Duration `yaml:"travel_time,omitempty" json:"-"`
+
+	// PollInterval, if set, is the interval at which devices that must be
+	// polled, like energy meters, are read while they are monitored.
+	PollInterval time.Duration `yaml:"poll_interval,omitempty" json:"-"`
+}
+
+// ConfigurationButton represents a button of a device in the configuration.
+type ConfigurationButton struct {
+	Group Group  `yaml:"group" json:"group"`
+	Name  string `yaml:"name" json:"description"`
+	Alias string `yaml:"alias" json:"id"`
+}
+
+// LookupButton finds a button of the device from its alias or its group.
+func (d *ConfigurationDevice) LookupButton(alias string) (*ConfigurationButton, error) {
+	for _, button := range d.Buttons {
+		if button.Alias == alias || fmt.Sprintf("%d", button.Group) == alias {
+			return &button, nil
+		}
+	}
+
+	if group, err := parseButton(alias); err == nil {
+		return &ConfigurationButton{Group: group}, nil
+	}
+
+	return nil, fmt.Errorf("no such button on device %s: %s", d.Alias, alias)
 }
 
 // UnmarshalYAML -
@@ -82,6 +138,16 @@ func (d *ConfigurationDevice) UnmarshalYAML(unmarshal func(interface{}) error) e
 		return fmt.Errorf("an alias must be defined")
 	}
 
+	if x.Outlet < 0 || x.Outlet > 2 {
+		return fmt.Errorf("the outlet of %s must be 1 or 2", x.Alias)
+	}
+
+	for _, button := range x.Buttons {
+		if button.Group == 0 {
+			return fmt.Errorf("a group must be defined for the buttons of %s", x.Alias)
+		}
+	}
+
 	*d = *(*ConfigurationDevice)(x)
 
 	return nil
@@ -97,6 +163,16 @@ func getUserConfigPath() (string, error) {
 	return filepath.Join(usr.HomeDir, ".config", "ion", "config.yml"), nil
 }
 
+func getUserStatePath(name string) (string, error) {
+	usr, err := user.Current()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(usr.HomeDir, ".config", "ion", name), nil
+}
+
 func getSystemConfigPath() string {
 	return "/etc/ion/config.yml"
 }
@@ -14,6 +14,10 @@ const (
 	// EnvPowerLineModemDebug is the environment variable that contains the
 	// system PowerLine Modem debug setting.
 	EnvPowerLineModemDebug = "INSTEON_POWERLINE_MODEM_DEBUG"
+	// EnvPowerLineModemFaults is the environment variable that contains the
+	// faults to inject in the system PowerLine Modem stream, for resilience
+	// testing.
+	EnvPowerLineModemFaults = "INSTEON_POWERLINE_MODEM_FAULTS"
 	// EnvWebServiceDebug is the environment variable that contains the
 	// system web-service debug setting.
 	EnvWebServiceDebug = "INSTEON_WEB_SERVICE_DEBUG"
@@ -22,7 +26,7 @@ const (
 const (
 	// DefaultPowerLineModemDevice is the default PowerLine Modem
 	// device.
-	DefaultPowerLineModemDevice = "/dev/tty0"
+	DefaultPowerLineModemDevice = AutoPowerLineModemDevice
 )
 
 var (
@@ -35,6 +39,9 @@ var (
 	// PowerLineModemDebug is the current PowerLine Modem debug setting.
 	PowerLineModemDebug = getBoolEnvOrDefault(EnvPowerLineModemDebug, false)
 
+	// PowerLineModemFaults is the current PowerLine Modem fault scenario.
+	PowerLineModemFaults = getFaultScenarioEnvOrDefault(EnvPowerLineModemFaults)
+
 	// WebServiceDebug is the current web-service debug setting.
 	WebServiceDebug = getBoolEnvOrDefault(EnvWebServiceDebug, false)
 )
@@ -69,3 +76,17 @@ func getBoolEnvOrDefault(env string, def bool) bool {
 
 	return def
 }
+
+func getFaultScenarioEnvOrDefault(env string) *FaultScenario {
+	if value := os.Getenv(env); value != "" {
+		scenario, err := ParseFaultScenario(value)
+
+		if err == nil {
+			return &scenario
+		}
+
+		fmt.Fprintf(os.Stderr, "Invalid value `%s` for fault scenario environment variable `%s`: %s.", value, env, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package insteon
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// AutoPowerLineModemDevice is the device value that triggers the
+	// auto-detection of the PowerLine Modem.
+	AutoPowerLineModemDevice = "auto"
+
+	// defaultDetectionTimeout is the time given to each serial port to
+	// answer during the detection.
+	defaultDetectionTimeout = time.Second
+)
+
+// ErrNoPowerLineModemDetected is returned when no PowerLine Modem could be
+// detected.
+var ErrNoPowerLineModemDetected = errors.New("no PowerLine Modem detected")
+
+// candidatePowerLineModemDevices contains the patterns of the serial ports
+// that may have a PowerLine Modem attached.
+//
+// Stable names come first so that they are the ones reported.
+var candidatePowerLineModemDevices = []string{
+	"/dev/serial/by-id/*",
+	"/dev/ttyUSB*",
+	"/dev/ttyACM*",
+}
+
+// DetectedPowerLineModem describes the result of the probing of a serial
+// port.
+type DetectedPowerLineModem struct {
+	Device string  `json:"device"`
+	IMInfo *IMInfo `json:"im_info,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// IsPowerLineModem returns whether the serial port has a PowerLine Modem
+// attached.
+func (d DetectedPowerLineModem) IsPowerLineModem() bool {
+	return d.IMInfo != nil && d.IMInfo.Category.MainCategory == networkBridges
+}
+
+// DetectPowerLineModems probes all the candidate serial ports and reports
+// which ones have a PowerLine Modem attached.
+//
+// Each port is given the specified timeout to answer.
+func DetectPowerLineModems(ctx context.Context, timeout time.Duration) []DetectedPowerLineModem {
+	var result []DetectedPowerLineModem
+
+	for _, device := range getCandidatePowerLineModemDevices() {
+		plm, imInfo, err := probePowerLineModem(ctx, device, timeout)
+		detected := DetectedPowerLineModem{
+			Device: device,
+			IMInfo: imInfo,
+		}
+
+		if err != nil {
+			detected.Error = err.Error()
+		} else {
+			plm.Close()
+		}
+
+		result = append(result, detected)
+	}
+
+	return result
+}
+
+// detectPowerLineModem returns the first PowerLine Modem detected.
+//
+// It returns a PowerLineModem, so that a failure yields a nil interface rather
+// than a nil *SerialPowerLineModem.
+func detectPowerLineModem(ctx context.Context) (PowerLineModem, error) {
+	for _, device := range getCandidatePowerLineModemDevices() {
+		plm, imInfo, err := probePowerLineModem(ctx, device, defaultDetectionTimeout)
+
+		if err != nil {
+			continue
+		}
+
+		if (DetectedPowerLineModem{IMInfo: imInfo}).IsPowerLineModem() {
+			return plm, nil
+		}
+
+		plm.Close()
+	}
+
+	return nil, ErrNoPowerLineModemDetected
+}
+
+// probePowerLineModem opens a serial port and asks for the IM information.
+//
+// On success, the PowerLine Modem is returned open.
+func probePowerLineModem(ctx context.Context, device string, timeout time.Duration) (*SerialPowerLineModem, *IMInfo, error) {
+	plm, err := NewLocalPowerLineModem(device)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	imInfo, err := plm.GetIMInfo(ctx)
+
+	if err != nil {
+		plm.Close()
+
+		return nil, nil, err
+	}
+
+	return plm, imInfo, nil
+}
+
+func getCandidatePowerLineModemDevices() []string {
+	var devices []string
+	seen := map[string]bool{}
+
+	for _, pattern := range candidatePowerLineModemDevices {
+		matches, _ := filepath.Glob(pattern)
+
+		for _, device := range matches {
+			path, err := filepath.EvalSymlinks(device)
+
+			if err != nil {
+				continue
+			}
+
+			if !seen[path] {
+				seen[path] = true
+				devices = append(devices, device)
+			}
+		}
+	}
+
+	return devices
+}
@@ -0,0 +1,732 @@
+package insteon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Device is a typed handle on an Insteon device.
+//
+// Handles are obtained through Devices.Device and implement the interfaces
+// that match their capabilities, such as Dimmer or Relay.
+type Device interface {
+	ID() ID
+	Category() Category
+	Driver() DeviceDriver
+	Beep(ctx context.Context) error
+}
+
+// Poller is implemented by the handles of devices that do not report their
+// state on their own and must be polled instead.
+type Poller interface {
+	Poll(ctx context.Context) (*DeviceEvent, error)
+}
+
+// Devices gives typed access to the devices of an Insteon network.
+type Devices struct {
+	PowerLineModem PowerLineModem
+
+	// Configuration, if set, is used to find the category of devices without
+	// asking them.
+	Configuration *Configuration
+
+	// Retries is the number of times a failed command is retried.
+	//
+	// Defaults to none, as PowerLine Modems already retry the messages that
+	// devices do not acknowledge.
+	Retries int
+
+	// RetryDelay is the delay between two attempts.
+	RetryDelay time.Duration
+
+	// CacheDuration is the duration during which device states are cached.
+	//
+	// A negative duration disables the cache.
+	CacheDuration time.Duration
+
+	// WakeUpTimeout is the time given to each queued command once the
+	// sleeping device it is for wakes up.
+	WakeUpTimeout time.Duration
+
+	once       sync.Once
+	lock       sync.Mutex
+	categories map[ID]Category
+	handles    map[ID]Device
+	cache      map[deviceCacheKey]deviceCacheEntry
+	lastEvents map[ID]DeviceEvent
+	lastSeen   map[ID]time.Time
+	attributes map[ID]map[string]interface{}
+	alarms     map[ID][]Alarm
+	queues     map[ID][]*queuedCommand
+	lastErrors map[ID]error
+
+	thermostatUnits map[ID]bool
+	failedLookups   map[ID]time.Time
+}
+
+// failedLookupDuration is the time during which the category of a device is
+// not looked up again after a failed lookup.
+const failedLookupDuration = time.Second * 30
+
+// queuedCommand is a command waiting for a sleeping device to wake up.
+type queuedCommand struct {
+	Name   string
+	Fn     func(context.Context, PowerLineModem) error
+	Result chan error
+}
+
+type deviceCacheKey struct {
+	ID   ID
+	Name string
+}
+
+type deviceCacheEntry struct {
+	Value     interface{}
+	Timestamp time.Time
+}
+
+// NewDevices instantiates a new set of typed devices.
+//
+// If no configuration is specified, device categories are always looked up.
+func NewDevices(powerLineModem PowerLineModem, configuration *Configuration) *Devices {
+	return &Devices{
+		PowerLineModem: powerLineModem,
+		Configuration:  configuration,
+	}
+}
+
+// Device returns a typed handle on a device.
+//
+// The category of the device is taken from the configuration if it is
+// specified there, and looked up otherwise.
+func (d *Devices) Device(ctx context.Context, identity ID) (Device, error) {
+	d.init()
+
+	d.lock.Lock()
+	handle := d.handles[identity]
+	d.lock.Unlock()
+
+	if handle != nil {
+		return handle, nil
+	}
+
+	category, err := d.getCategory(ctx, identity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	handle = d.newDevice(identity, category)
+
+	d.lock.Lock()
+	d.handles[identity] = handle
+	d.lock.Unlock()
+
+	return handle, nil
+}
+
+// deviceOrDefault returns a typed handle on a device or, if its category
+// can't be determined, like when it doesn't answer, a handle with the default
+// driver.
+//
+// The default handle is not kept, so that the category is looked up again
+// later, but not before failedLookupDuration, so that requests don't all wait
+// for the lookup to fail.
+func (d *Devices) deviceOrDefault(ctx context.Context, identity ID) Device {
+	d.init()
+
+	d.lock.Lock()
+	handle := d.handles[identity]
+	failedAt, failed := d.failedLookups[identity]
+	d.lock.Unlock()
+
+	if handle != nil {
+		return handle
+	}
+
+	if !failed || time.Since(failedAt) > failedLookupDuration {
+		device, err := d.Device(ctx, identity)
+
+		d.lock.Lock()
+
+		if err == nil {
+			delete(d.failedLookups, identity)
+		} else {
+			d.failedLookups[identity] = time.Now()
+		}
+
+		d.lock.Unlock()
+
+		if err == nil {
+			return device
+		}
+	}
+
+	return defaultDeviceDriver.NewDevice(&BaseDevice{
+		devices: d,
+		driver:  defaultDeviceDriver,
+		id:      identity,
+	})
+}
+
+// GetState returns the state of a device, as defined by its driver.
+//
+// Devices of unknown categories are handled by the default driver.
+func (d *Devices) GetState(ctx context.Context, identity ID) (interface{}, error) {
+	device := d.deviceOrDefault(ctx, identity)
+
+	return device.Driver().GetState(ctx, device)
+}
+
+// SetState sets the state of a device, as defined by its driver.
+//
+// Devices of unknown categories are handled by the default driver.
+func (d *Devices) SetState(ctx context.Context, identity ID, state interface{}) error {
+	device := d.deviceOrDefault(ctx, identity)
+
+	return device.Driver().SetState(ctx, device, state)
+}
+
+// GetInfo returns the information about a device, as defined by its driver.
+//
+// Devices of unknown categories are handled by the default driver.
+func (d *Devices) GetInfo(ctx context.Context, identity ID) (interface{}, error) {
+	device := d.deviceOrDefault(ctx, identity)
+
+	return device.Driver().GetInfo(ctx, device)
+}
+
+// SetInfo sets the information on a device, as defined by its driver.
+//
+// Devices of unknown categories are handled by the default driver.
+func (d *Devices) SetInfo(ctx context.Context, identity ID, info interface{}) error {
+	device := d.deviceOrDefault(ctx, identity)
+
+	return device.Driver().SetInfo(ctx, device, info)
+}
+
+// RunCommand runs a command of the driver of a device.
+func (d *Devices) RunCommand(ctx context.Context, identity ID, name string, args []string) (interface{}, error) {
+	device, err := d.Device(ctx, identity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	command, ok := GetDeviceCommand(device.Driver(), name)
+
+	if !ok {
+		return nil, ErrNoSuchCommand{Driver: device.Driver().Name(), Command: name}
+	}
+
+	return command.Run(ctx, device, args)
+}
+
+// Monitor the Insteon network for as long as the specified context remains
+// valid, keeping the cached device states up to date and sending the queued
+// commands of the devices that wake up.
+//
+// Events are decoded by the drivers of their devices and pushed to the
+// specified events channel.
+func (d *Devices) Monitor(ctx context.Context, events chan<- DeviceEvent) error {
+	d.init()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rawEvents := make(chan DeviceEvent, 10)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- d.PowerLineModem.Monitor(ctx, rawEvents)
+	}()
+
+	if d.Configuration != nil {
+		for _, device := range d.Configuration.Devices {
+			if device.PollInterval > 0 {
+				go d.poll(ctx, device.ID, device.PollInterval, rawEvents)
+			}
+		}
+	}
+
+	for {
+		select {
+		case event := <-rawEvents:
+			if !d.decodeEvent(&event) {
+				continue
+			}
+
+			d.observe(&event)
+			d.flushQueue(ctx, event.Identity)
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// poll polls a device at a regular interval, for as long as the specified
+// context remains valid, if it is a poller.
+func (d *Devices) poll(ctx context.Context, identity ID, interval time.Duration, events chan<- DeviceEvent) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		device, err := d.Device(ctx, identity)
+
+		if err != nil {
+			continue
+		}
+
+		poller, ok := device.(Poller)
+
+		if !ok {
+			return
+		}
+
+		event, err := poller.Poll(ctx)
+
+		if err != nil {
+			continue
+		}
+
+		select {
+		case events <- *event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Invalidate the cached states of a device.
+func (d *Devices) Invalidate(identity ID) {
+	d.init()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for key := range d.cache {
+		if key.ID == identity {
+			delete(d.cache, key)
+		}
+	}
+}
+
+func (d *Devices) init() {
+	d.once.Do(func() {
+		if d.PowerLineModem == nil {
+			d.PowerLineModem = DefaultPowerLineModem
+		}
+
+		if d.RetryDelay == 0 {
+			d.RetryDelay = time.Millisecond * 500
+		}
+
+		if d.CacheDuration == 0 {
+			d.CacheDuration = time.Minute
+		}
+
+		if d.WakeUpTimeout == 0 {
+			d.WakeUpTimeout = time.Second * 10
+		}
+
+		d.categories = map[ID]Category{}
+		d.handles = map[ID]Device{}
+		d.cache = map[deviceCacheKey]deviceCacheEntry{}
+		d.lastEvents = map[ID]DeviceEvent{}
+		d.lastSeen = map[ID]time.Time{}
+		d.attributes = map[ID]map[string]interface{}{}
+		d.alarms = map[ID][]Alarm{}
+		d.queues = map[ID][]*queuedCommand{}
+		d.lastErrors = map[ID]error{}
+		d.thermostatUnits = map[ID]bool{}
+		d.failedLookups = map[ID]time.Time{}
+	})
+}
+
+func (d *Devices) getCategory(ctx context.Context, identity ID) (Category, error) {
+	category, ok := d.getKnownCategory(identity)
+
+	if ok {
+		return category, nil
+	}
+
+	err := d.retry(ctx, func(ctx context.Context) error {
+		c, err := d.PowerLineModem.GetDeviceCategory(ctx, identity)
+
+		if err == nil {
+			category = *c
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return category, err
+	}
+
+	d.lock.Lock()
+	d.categories[identity] = category
+	d.lock.Unlock()
+
+	return category, nil
+}
+
+// getKnownCategory returns the category of a device, if it is known without
+// asking the device.
+func (d *Devices) getKnownCategory(identity ID) (Category, bool) {
+	if d.Configuration != nil {
+		if device, err := d.Configuration.GetDevice(identity); err == nil && device.Category != nil {
+			return *device.Category, true
+		}
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	category, ok := d.categories[identity]
+
+	return category, ok
+}
+
+func (d *Devices) newDevice(identity ID, category Category) Device {
+	driver := GetDeviceDriver(category)
+
+	return driver.NewDevice(&BaseDevice{
+		devices:  d,
+		driver:   driver,
+		id:       identity,
+		category: category,
+	})
+}
+
+// decodeEvent completes an event with the driver of its device.
+//
+// Devices are never asked for their category here, as the ones that send
+// events are often asleep.
+func (d *Devices) decodeEvent(event *DeviceEvent) bool {
+	if event.Message == nil {
+		return true
+	}
+
+	driver := defaultDeviceDriver
+
+	if category, ok := d.getKnownCategory(event.Identity); ok {
+		driver = GetDeviceDriver(category)
+	}
+
+	// Drivers set the type of the events they decode.
+	if event.Type == MessageEventType {
+		event.Type = ""
+	}
+
+	if !driver.DecodeEvent(event.Message, event) {
+		return false
+	}
+
+	d.convertTemperatures(event)
+
+	return true
+}
+
+func (d *Devices) observe(event *DeviceEvent) {
+	d.Invalidate(event.Identity)
+
+	if event.Alarm {
+		event.Raised = d.latchAlarms(event.Identity, event.Attributes)
+	}
+
+	d.lock.Lock()
+	d.lastEvents[event.Identity] = *event
+	d.lastSeen[event.Identity] = time.Now().UTC()
+	d.lock.Unlock()
+
+	d.setAttributes(event.Identity, event.Attributes)
+}
+
+// setAttributes merges attributes into the known attributes of a device.
+func (d *Devices) setAttributes(identity ID, attributes map[string]interface{}) {
+	if len(attributes) == 0 {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	known := d.attributes[identity]
+
+	if known == nil {
+		known = map[string]interface{}{}
+		d.attributes[identity] = known
+	}
+
+	for key, value := range attributes {
+		known[key] = value
+	}
+}
+
+// flushQueue sends the queued commands of a device that just showed it is
+// awake.
+//
+// Commands that time out are queued again, in order, as the device most
+// likely went back to sleep. The error of the last command sent is recorded,
+// as callers rarely wait for the results.
+func (d *Devices) flushQueue(ctx context.Context, identity ID) {
+	d.lock.Lock()
+	queue := d.queues[identity]
+	delete(d.queues, identity)
+	d.lock.Unlock()
+
+	if len(queue) == 0 {
+		return
+	}
+
+	go func() {
+		defer d.Invalidate(identity)
+
+		for i, command := range queue {
+			commandCtx, cancel := context.WithTimeout(ctx, d.WakeUpTimeout)
+			err := command.Fn(commandCtx, d.PowerLineModem)
+			cancel()
+
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				d.lock.Lock()
+				d.queues[identity] = append(queue[i:], d.queues[identity]...)
+				d.lock.Unlock()
+
+				return
+			}
+
+			d.lock.Lock()
+			d.lastErrors[identity] = err
+			d.lock.Unlock()
+
+			command.Result <- err
+			close(command.Result)
+		}
+	}()
+}
+
+// retry calls a function until it succeeds, it gets refused by the device or
+// the retries are exhausted.
+func (d *Devices) retry(ctx context.Context, fn func(context.Context) error) (err error) {
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(d.RetryDelay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+
+		var nak ErrDeviceNak
+
+		if errors.As(err, &nak) || errors.Is(err, ErrNotSupported) || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// cached returns the cached value for a device, or gets and caches it.
+func (d *Devices) cached(ctx context.Context, identity ID, name string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	key := deviceCacheKey{ID: identity, Name: name}
+
+	d.lock.Lock()
+	entry, ok := d.cache[key]
+	d.lock.Unlock()
+
+	if ok && time.Since(entry.Timestamp) < d.CacheDuration {
+		return entry.Value, nil
+	}
+
+	var value interface{}
+
+	err := d.retry(ctx, func(ctx context.Context) (err error) {
+		value, err = fn(ctx)
+		return
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if d.CacheDuration > 0 {
+		d.lock.Lock()
+		d.cache[key] = deviceCacheEntry{Value: value, Timestamp: time.Now().UTC()}
+		d.lock.Unlock()
+	}
+
+	return value, nil
+}
+
+// BaseDevice is the base of all device handles.
+//
+// Device drivers build their handles on top of it, and use its methods to
+// talk to the device with retries and caching.
+type BaseDevice struct {
+	devices  *Devices
+	driver   DeviceDriver
+	id       ID
+	category Category
+}
+
+// ID returns the identity of the device.
+func (d *BaseDevice) ID() ID {
+	return d.id
+}
+
+// Category returns the category of the device.
+func (d *BaseDevice) Category() Category {
+	return d.category
+}
+
+// Driver returns the driver of the device.
+func (d *BaseDevice) Driver() DeviceDriver {
+	return d.driver
+}
+
+// Config returns the configuration of the device, or nil if it has none.
+func (d *BaseDevice) Config() *ConfigurationDevice {
+	if d.devices.Configuration == nil {
+		return nil
+	}
+
+	device, err := d.devices.Configuration.GetDevice(d.id)
+
+	if err != nil {
+		return nil
+	}
+
+	return device
+}
+
+// Beep causes the device to beep.
+func (d *BaseDevice) Beep(ctx context.Context) error {
+	return d.devices.retry(ctx, func(ctx context.Context) error {
+		return d.devices.PowerLineModem.Beep(ctx, d.id)
+	})
+}
+
+// Do executes a command on the device, with retries, and invalidates its
+// cached states.
+func (d *BaseDevice) Do(ctx context.Context, fn func(context.Context, PowerLineModem) error) error {
+	defer d.devices.Invalidate(d.id)
+
+	return d.devices.retry(ctx, func(ctx context.Context) error {
+		return fn(ctx, d.devices.PowerLineModem)
+	})
+}
+
+// SendCommand sends a command to the device, with retries, and invalidates
+// its cached states.
+func (d *BaseDevice) SendCommand(ctx context.Context, command Command) (response *CommandResponse, err error) {
+	err = d.Do(ctx, func(ctx context.Context, plm PowerLineModem) (err error) {
+		response, err = plm.SendCommand(ctx, d.id, command)
+		return
+	})
+
+	return
+}
+
+// Cached returns a cached value of the device, or gets and caches it.
+//
+// Cached values are invalidated by commands and events.
+func (d *BaseDevice) Cached(ctx context.Context, name string, fn func(context.Context, PowerLineModem) (interface{}, error)) (interface{}, error) {
+	return d.devices.cached(ctx, d.id, name, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx, d.devices.PowerLineModem)
+	})
+}
+
+// Enqueue queues a command for the device, to be sent right after its next
+// broadcast, when it is known to be awake. This is how sleeping devices,
+// like battery-powered sensors, are configured.
+//
+// Queued commands are only sent while Devices.Monitor runs. The returned
+// channel receives the result of the command.
+func (d *BaseDevice) Enqueue(name string, fn func(context.Context, PowerLineModem) error) <-chan error {
+	command := &queuedCommand{
+		Name:   name,
+		Fn:     fn,
+		Result: make(chan error, 1),
+	}
+
+	d.devices.init()
+
+	d.devices.lock.Lock()
+	d.devices.queues[d.id] = append(d.devices.queues[d.id], command)
+	d.devices.lock.Unlock()
+
+	return command.Result
+}
+
+// PendingCommands returns the names of the queued commands of the device.
+func (d *BaseDevice) PendingCommands() []string {
+	d.devices.lock.Lock()
+	defer d.devices.lock.Unlock()
+
+	var names []string
+
+	for _, command := range d.devices.queues[d.id] {
+		names = append(names, command.Name)
+	}
+
+	return names
+}
+
+// LastCommandError returns the error of the last queued command sent to the
+// device, if it failed.
+func (d *BaseDevice) LastCommandError() error {
+	d.devices.lock.Lock()
+	defer d.devices.lock.Unlock()
+
+	return d.devices.lastErrors[d.id]
+}
+
+// Attributes returns the last value of each of the event attributes
+// received from the device.
+func (d *BaseDevice) Attributes() map[string]interface{} {
+	d.devices.lock.Lock()
+	defer d.devices.lock.Unlock()
+
+	attributes := make(map[string]interface{}, len(d.devices.attributes[d.id]))
+
+	for key, value := range d.devices.attributes[d.id] {
+		attributes[key] = value
+	}
+
+	return attributes
+}
+
+// LastEvent returns the last event received from the device, if any, and
+// when it was received.
+func (d *BaseDevice) LastEvent() (*DeviceEvent, time.Time) {
+	d.devices.lock.Lock()
+	defer d.devices.lock.Unlock()
+
+	event, ok := d.devices.lastEvents[d.id]
+
+	if !ok {
+		return nil, time.Time{}
+	}
+
+	return &event, d.devices.lastSeen[d.id]
+}
@@ -0,0 +1,258 @@
+package insteon
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DeviceDriver implements the support of a family of devices.
+//
+// Drivers are registered for the categories of the devices they support,
+// with RegisterDeviceDriver or RegisterMainCategoryDeviceDriver. The web
+// API, the CLI and the monitor then pick them up.
+type DeviceDriver interface {
+	// Name returns the name of the driver.
+	Name() string
+
+	// NewDevice returns a typed handle on a device.
+	NewDevice(base *BaseDevice) Device
+
+	// DecodeEvent completes an event from the message it was received with.
+	//
+	// It returns false if the message is not an event for the driver.
+	DecodeEvent(msg *Message, event *DeviceEvent) bool
+
+	// NewState and NewInfo return new values of the state and information
+	// of the devices, or nil if they have none. They define the schemas of
+	// the state and information.
+	NewState() interface{}
+	NewInfo() interface{}
+
+	// GetState, SetState, GetInfo and SetInfo exchange values of the types
+	// returned by NewState and NewInfo.
+	GetState(ctx context.Context, device Device) (interface{}, error)
+	SetState(ctx context.Context, device Device, state interface{}) error
+	GetInfo(ctx context.Context, device Device) (interface{}, error)
+	SetInfo(ctx context.Context, device Device, info interface{}) error
+
+	// Commands returns the specific commands of the devices.
+	Commands() []DeviceCommand
+}
+
+// DeviceCommand is a named command supported by a device driver.
+type DeviceCommand struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage,omitempty"`
+	Description string `json:"description"`
+
+	// Run the command on a device, with the specified arguments, and return
+	// its result, if any.
+	Run func(ctx context.Context, device Device, args []string) (interface{}, error) `json:"-"`
+}
+
+// DeviceDriverDescription describes a device driver.
+type DeviceDriverDescription struct {
+	Name     string            `json:"name"`
+	State    map[string]string `json:"state,omitempty"`
+	Info     map[string]string `json:"info,omitempty"`
+	Commands []DeviceCommand   `json:"commands,omitempty"`
+}
+
+// BaseDeviceDriver provides the default behavior of device drivers.
+//
+// It is meant to be embedded in drivers, which only have to override what
+// they support.
+type BaseDeviceDriver struct{}
+
+// NewDevice returns the base device handle.
+func (BaseDeviceDriver) NewDevice(base *BaseDevice) Device {
+	return base
+}
+
+// DecodeEvent decodes light state changes.
+func (BaseDeviceDriver) DecodeEvent(msg *Message, event *DeviceEvent) bool {
+	state := &LightState{}
+
+	if err := state.UnmarshalBinary(msg.CommandBytes[:]); err != nil {
+		return false
+	}
+
+	event.OnOff = state.OnOff
+	event.Change = state.Change
+
+	return true
+}
+
+// NewState returns nil.
+func (BaseDeviceDriver) NewState() interface{} {
+	return nil
+}
+
+// NewInfo returns nil.
+func (BaseDeviceDriver) NewInfo() interface{} {
+	return nil
+}
+
+// GetState returns ErrNotSupported.
+func (BaseDeviceDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	return nil, ErrNotSupported
+}
+
+// SetState returns ErrNotSupported.
+func (BaseDeviceDriver) SetState(ctx context.Context, device Device, state interface{}) error {
+	return ErrNotSupported
+}
+
+// GetInfo returns ErrNotSupported.
+func (BaseDeviceDriver) GetInfo(ctx context.Context, device Device) (interface{}, error) {
+	return nil, ErrNotSupported
+}
+
+// SetInfo returns ErrNotSupported.
+func (BaseDeviceDriver) SetInfo(ctx context.Context, device Device, info interface{}) error {
+	return ErrNotSupported
+}
+
+// Commands returns no commands.
+func (BaseDeviceDriver) Commands() []DeviceCommand {
+	return nil
+}
+
+var deviceDrivers = struct {
+	sync.Mutex
+	ByCategory     map[Category]DeviceDriver
+	ByMainCategory map[MainCategory]DeviceDriver
+	ByName         map[string]DeviceDriver
+}{
+	ByCategory:     map[Category]DeviceDriver{},
+	ByMainCategory: map[MainCategory]DeviceDriver{},
+	ByName:         map[string]DeviceDriver{},
+}
+
+// RegisterDeviceDriver registers a device driver for specific categories.
+//
+// Registering a driver for a category that already has one replaces it.
+func RegisterDeviceDriver(driver DeviceDriver, categories ...Category) {
+	deviceDrivers.Lock()
+	defer deviceDrivers.Unlock()
+
+	for _, category := range categories {
+		deviceDrivers.ByCategory[category] = driver
+	}
+
+	deviceDrivers.ByName[driver.Name()] = driver
+}
+
+// RegisterMainCategoryDeviceDriver registers a device driver for all the
+// categories of a main category that have no specific driver.
+func RegisterMainCategoryDeviceDriver(driver DeviceDriver, mainCategory MainCategory) {
+	deviceDrivers.Lock()
+	defer deviceDrivers.Unlock()
+
+	deviceDrivers.ByMainCategory[mainCategory] = driver
+	deviceDrivers.ByName[driver.Name()] = driver
+}
+
+// GetDeviceDriver returns the device driver for a category.
+//
+// Devices of unsupported categories are handled as lights.
+func GetDeviceDriver(category Category) DeviceDriver {
+	deviceDrivers.Lock()
+	defer deviceDrivers.Unlock()
+
+	if driver, ok := deviceDrivers.ByCategory[category]; ok {
+		return driver
+	}
+
+	if driver, ok := deviceDrivers.ByMainCategory[category.MainCategory]; ok {
+		return driver
+	}
+
+	return defaultDeviceDriver
+}
+
+// DeviceDrivers returns the registered device drivers, sorted by name.
+func DeviceDrivers() []DeviceDriver {
+	deviceDrivers.Lock()
+	defer deviceDrivers.Unlock()
+
+	drivers := make([]DeviceDriver, 0, len(deviceDrivers.ByName))
+
+	for _, driver := range deviceDrivers.ByName {
+		drivers = append(drivers, driver)
+	}
+
+	sort.Slice(drivers, func(i, j int) bool { return drivers[i].Name() < drivers[j].Name() })
+
+	return drivers
+}
+
+// DescribeDeviceDriver returns the description of a device driver.
+func DescribeDeviceDriver(driver DeviceDriver) DeviceDriverDescription {
+	return DeviceDriverDescription{
+		Name:     driver.Name(),
+		State:    describeSchema(driver.NewState()),
+		Info:     describeSchema(driver.NewInfo()),
+		Commands: driver.Commands(),
+	}
+}
+
+// GetDeviceCommand returns the command of a driver with the specified name.
+func GetDeviceCommand(driver DeviceDriver, name string) (DeviceCommand, bool) {
+	for _, command := range driver.Commands() {
+		if command.Name == name {
+			return command, true
+		}
+	}
+
+	return DeviceCommand{}, false
+}
+
+// describeSchema returns the JSON fields of a value and their types.
+func describeSchema(value interface{}) map[string]string {
+	if value == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(value)
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	schema := map[string]string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldType := field.Type
+
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		schema[name] = fieldType.Name()
+
+		if schema[name] == "" {
+			schema[name] = fieldType.String()
+		}
+	}
+
+	return schema
+}
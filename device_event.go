@@ -1,8 +1,72 @@
 package insteon
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MessageEventType is the type of the events of the messages that are not
+// light state changes, before device drivers decode them. Their OnOff and
+// Change are meaningless.
+const MessageEventType = "message"
+
 // DeviceEvent represents a DeviceEvent.
 type DeviceEvent struct {
 	Identity ID               `json:"id"`
 	OnOff    LightOnOff       `json:"onoff"`
 	Change   LightStateChange `json:"change,omitempty"`
+
+	// Group is the group of the device the event is about.
+	Group Group `json:"group,omitempty"`
+
+	// Type and Attributes are set by device drivers for the events that are
+	// not simple light state changes.
+	Type       string                 `json:"type,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+
+	// Alarm is set for the events that report alarm conditions, like smoke
+	// or water leaks, in their attributes. These conditions are latched.
+	Alarm bool `json:"alarm,omitempty"`
+
+	// Raised are the alarm conditions that the event made active.
+	Raised []string `json:"raised,omitempty"`
+
+	// Message is the message the event was received with, if any.
+	Message *Message `json:"message,omitempty"`
+}
+
+func (e DeviceEvent) String() string {
+	result := fmt.Sprintf("%s", e.Identity)
+
+	if e.Group != 0 {
+		result += fmt.Sprintf(" group %d", e.Group)
+	}
+
+	if e.Type != "" {
+		result += " " + e.Type
+	} else {
+		change, _ := e.Change.MarshalText()
+		result += fmt.Sprintf(" %s (%s)", e.OnOff, change)
+	}
+
+	if len(e.Attributes) > 0 {
+		keys := make([]string, 0, len(e.Attributes))
+
+		for key := range e.Attributes {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		attributes := make([]string, len(keys))
+
+		for i, key := range keys {
+			attributes[i] = fmt.Sprintf("%s=%v", key, e.Attributes[key])
+		}
+
+		result += " " + strings.Join(attributes, " ")
+	}
+
+	return result
 }
@@ -0,0 +1,79 @@
+package insteon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrDeviceLocked is returned when a device is already in use by another
+// process.
+type ErrDeviceLocked struct {
+	Device      string
+	PID         int
+	CommandLine string
+}
+
+// Error returns the error string.
+func (e ErrDeviceLocked) Error() string {
+	if e.CommandLine != "" {
+		return fmt.Sprintf("device %s is in use by process %d (%s)", e.Device, e.PID, e.CommandLine)
+	}
+
+	return fmt.Sprintf("device %s is in use by process %d", e.Device, e.PID)
+}
+
+// getDeviceLockPath returns the path of the UUCP-style lock file of a
+// device.
+//
+// Symbolic links are resolved so that all the names of a device share the
+// same lock.
+func getDeviceLockPath(device string) string {
+	if path, err := filepath.EvalSymlinks(device); err == nil {
+		device = path
+	}
+
+	name := "LCK.." + filepath.Base(device)
+
+	// Not all systems let regular users write in /var/lock.
+	for _, dir := range []string{"/var/lock", "/run/lock"} {
+		if isWritableDir(dir) {
+			return filepath.Join(dir, name)
+		}
+	}
+
+	return filepath.Join(os.TempDir(), name)
+}
+
+func isWritableDir(dir string) bool {
+	f, err := ioutil.TempFile(dir, ".ion-lock-check")
+
+	if err != nil {
+		return false
+	}
+
+	f.Close()
+	os.Remove(f.Name())
+
+	return true
+}
+
+// formatDeviceLock returns the content of a lock file.
+func formatDeviceLock() []byte {
+	return []byte(fmt.Sprintf("%10d\n%s\n", os.Getpid(), strings.Join(os.Args, " ")))
+}
+
+// parseDeviceLock parses the content of a lock file.
+func parseDeviceLock(data []byte) (pid int, commandLine string) {
+	lines := strings.SplitN(string(data), "\n", 3)
+	pid, _ = strconv.Atoi(strings.TrimSpace(lines[0]))
+
+	if len(lines) > 1 {
+		commandLine = strings.TrimSpace(lines[1])
+	}
+
+	return
+}
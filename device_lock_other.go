@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package insteon
+
+// lockDevice is a no-op on systems without flock(2): the operating system
+// usually prevents serial ports from being opened twice.
+func lockDevice(device string) (func(), error) {
+	return func() {}, nil
+}
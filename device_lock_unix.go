@@ -0,0 +1,103 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package insteon
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// lockDevice takes an advisory lock on a device, and returns the function
+// that releases it.
+//
+// Stale locks, left by processes that are gone, are recovered.
+func lockDevice(device string) (func(), error) {
+	path := getDeviceLockPath(device)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %s", err)
+	}
+
+	data, _ := ioutil.ReadAll(f)
+	pid, commandLine := parseDeviceLock(data)
+
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+
+		if err == syscall.EWOULDBLOCK {
+			return nil, ErrDeviceLocked{Device: device, PID: pid, CommandLine: commandLine}
+		}
+
+		return nil, fmt.Errorf("locking %s: %s", path, err)
+	}
+
+	// Programs that use UUCP-style lock files without flock(2) are only
+	// detected by the PID they wrote, which may have been reused since.
+	if pid != 0 && pid != os.Getpid() && isProcessAlive(pid) && isProcessCommandLine(pid, commandLine) {
+		f.Close()
+
+		return nil, ErrDeviceLocked{Device: device, PID: pid, CommandLine: commandLine}
+	}
+
+	if pid != 0 && pid != os.Getpid() {
+		fmt.Fprintf(os.Stderr, "Recovering stale lock of %s left by process %d.\n", device, pid)
+	}
+
+	if err = writeDeviceLock(f); err != nil {
+		f.Close()
+
+		return nil, fmt.Errorf("writing lock file: %s", err)
+	}
+
+	// Unlinking the file on release would let a process lock a new file of
+	// the same name while another one still holds the old one: it is only
+	// emptied instead.
+	return func() {
+		f.Truncate(0)
+		f.Close()
+	}, nil
+}
+
+func writeDeviceLock(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err := f.Write(formatDeviceLock())
+
+	return err
+}
+
+// isProcessCommandLine tells whether a process runs the specified command
+// line, assuming it does when that can't be told.
+func isProcessCommandLine(pid int, commandLine string) bool {
+	if commandLine == "" {
+		return true
+	}
+
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+
+	if err != nil {
+		return true
+	}
+
+	args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+
+	return strings.Join(args, " ") == commandLine
+}
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,137 @@
+package insteon
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// PingResult contains the result of a single ping to a device.
+type PingResult struct {
+	Latency  time.Duration `json:"latency"`
+	Hops     int           `json:"hops"`
+	HopsUsed int           `json:"hops_used"`
+	Nak      *NakReason    `json:"nak,omitempty"`
+}
+
+// LatencyPercentiles contains latency percentiles.
+type LatencyPercentiles struct {
+	Min time.Duration `json:"min"`
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// LinkReport contains a link quality report for a device.
+type LinkReport struct {
+	ID       ID                 `json:"id"`
+	Sent     int                `json:"sent"`
+	Received int                `json:"received"`
+	Loss     float64            `json:"loss"`
+	Latency  LatencyPercentiles `json:"latency"`
+	HopsUsed map[int]int        `json:"hops_used"`
+	Naks     map[NakReason]int  `json:"naks,omitempty"`
+	Errors   []string           `json:"errors,omitempty"`
+}
+
+// Diagnose pings a device repeatedly and returns a link quality report.
+//
+// Pings are spaced by the specified interval. The diagnosis stops early if
+// the context expires, and the report covers the pings sent so far.
+func Diagnose(ctx context.Context, plm PowerLineModem, identity ID, count int, interval time.Duration, results chan<- PingResult) *LinkReport {
+	report := &LinkReport{
+		ID:       identity,
+		HopsUsed: map[int]int{},
+		Naks:     map[NakReason]int{},
+	}
+
+	var latencies []time.Duration
+
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return report.finalize(latencies)
+			}
+		}
+
+		result, err := plm.Ping(ctx, identity)
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		report.Sent++
+
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+
+		report.Received++
+		report.HopsUsed[result.HopsUsed]++
+		latencies = append(latencies, result.Latency)
+
+		if result.Nak != nil {
+			report.Naks[*result.Nak]++
+		}
+
+		if results != nil {
+			results <- *result
+		}
+	}
+
+	return report.finalize(latencies)
+}
+
+// DiagnoseAll diagnoses several devices, one after the other.
+func DiagnoseAll(ctx context.Context, plm PowerLineModem, identities []ID, count int, interval time.Duration) []*LinkReport {
+	reports := make([]*LinkReport, 0, len(identities))
+
+	for _, identity := range identities {
+		if ctx.Err() != nil {
+			break
+		}
+
+		reports = append(reports, Diagnose(ctx, plm, identity, count, interval, nil))
+	}
+
+	return reports
+}
+
+func (r *LinkReport) finalize(latencies []time.Duration) *LinkReport {
+	if r.Sent > 0 {
+		r.Loss = float64(r.Sent-r.Received) / float64(r.Sent)
+	}
+
+	if len(latencies) == 0 {
+		return r
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	r.Latency = LatencyPercentiles{
+		Min: latencies[0],
+		P50: percentile(latencies, 0.5),
+		P90: percentile(latencies, 0.9),
+		P99: percentile(latencies, 0.99),
+		Max: latencies[len(latencies)-1],
+	}
+
+	return r
+}
+
+// percentile returns the p-th percentile of sorted values.
+func percentile(values []time.Duration, p float64) time.Duration {
+	i := int(float64(len(values))*p+0.5) - 1
+
+	if i < 0 {
+		i = 0
+	} else if i >= len(values) {
+		i = len(values) - 1
+	}
+
+	return values[i]
+}
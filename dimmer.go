@@ -0,0 +1,160 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Dimmer is a device that controls the level of a load.
+type Dimmer interface {
+	Relay
+	GetLevel(ctx context.Context) (float64, error)
+	SetLevel(ctx context.Context, level float64) error
+	GetInfo(ctx context.Context) (*DeviceInfo, error)
+	SetInfo(ctx context.Context, deviceInfo DeviceInfo) error
+}
+
+type dimmer struct {
+	relay
+}
+
+// GetLevel returns the level of the load.
+func (d *dimmer) GetLevel(ctx context.Context) (float64, error) {
+	state, err := d.getLightState(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return state.Level, nil
+}
+
+// SetLevel sets the level of the load.
+func (d *dimmer) SetLevel(ctx context.Context, level float64) error {
+	if level <= 0 {
+		return d.Off(ctx)
+	}
+
+	return d.setLightState(ctx, LightState{OnOff: LightOn, Level: level})
+}
+
+// GetInfo returns the information about the device.
+func (d *dimmer) GetInfo(ctx context.Context) (*DeviceInfo, error) {
+	value, err := d.Cached(ctx, "info", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		return plm.GetDeviceInfo(ctx, d.id)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*DeviceInfo), nil
+}
+
+// SetInfo sets the information on the device.
+func (d *dimmer) SetInfo(ctx context.Context, deviceInfo DeviceInfo) error {
+	return d.Do(ctx, func(ctx context.Context, plm PowerLineModem) error {
+		return plm.SetDeviceInfo(ctx, d.id, deviceInfo)
+	})
+}
+
+// lightDriver is the driver of dimmable lights.
+//
+// It is also the driver of devices of unknown categories, as most Insteon
+// devices are lights.
+type lightDriver struct {
+	switchDriver
+}
+
+var defaultDeviceDriver DeviceDriver = lightDriver{}
+
+func (lightDriver) Name() string {
+	return "light"
+}
+
+func (lightDriver) NewDevice(base *BaseDevice) Device {
+	return &dimmer{relay{base}}
+}
+
+func (lightDriver) NewInfo() interface{} {
+	return &DeviceInfo{}
+}
+
+func (lightDriver) GetInfo(ctx context.Context, device Device) (interface{}, error) {
+	dimmer, err := asDimmer(device)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dimmer.GetInfo(ctx)
+}
+
+func (lightDriver) SetInfo(ctx context.Context, device Device, info interface{}) error {
+	dimmer, err := asDimmer(device)
+
+	if err != nil {
+		return err
+	}
+
+	deviceInfo, ok := info.(*DeviceInfo)
+
+	if !ok {
+		return fmt.Errorf("unexpected information type: %T", info)
+	}
+
+	return dimmer.SetInfo(ctx, *deviceInfo)
+}
+
+func (lightDriver) Commands() []DeviceCommand {
+	return dimmerCommands
+}
+
+var dimmerCommands = append([]DeviceCommand{
+	{
+		Name:        "level",
+		Usage:       "<level>",
+		Description: "Set the level of the load, as a decimal value in the [0, 1] range",
+		Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+			dimmer, err := asDimmer(device)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if len(args) != 1 {
+				return nil, fmt.Errorf("expected a level")
+			}
+
+			level, err := strconv.ParseFloat(args[0], 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("parsing level: %s", err)
+			}
+
+			return nil, dimmer.SetLevel(ctx, level)
+		},
+	},
+}, relayCommands...)
+
+// asDimmer returns a device as a dimmer.
+//
+// Fans are handled as their light.
+func asDimmer(device Device) (Dimmer, error) {
+	if fan, ok := device.(Fan); ok {
+		return fan.Light(), nil
+	}
+
+	dimmer, ok := device.(Dimmer)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return dimmer, nil
+}
+
+func init() {
+	RegisterMainCategoryDeviceDriver(lightDriver{}, dimmableLightingControl)
+}
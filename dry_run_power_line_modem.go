@@ -0,0 +1,441 @@
+package insteon
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DryRunState is the simulated state of a dry-run PowerLine Modem.
+type DryRunState struct {
+	// ID is the identity of the simulated PowerLine Modem.
+	ID ID `yaml:"id"`
+	// Links are the records of the simulated All-Link DB.
+	Links []DryRunLink `yaml:"links"`
+	// Devices are the simulated devices, by identity.
+	Devices map[ID]*DryRunDevice `yaml:"devices"`
+}
+
+// DryRunLink is a simulated All-Link DB record.
+//
+// Controller sets the mode of the record to ModeController rather than
+// ModeResponder.
+type DryRunLink struct {
+	ID         ID    `yaml:"id"`
+	Group      Group `yaml:"group"`
+	Controller bool  `yaml:"controller"`
+}
+
+// DryRunDevice is a simulated device.
+type DryRunDevice struct {
+	Category      Category      `yaml:"category"`
+	Level         float64       `yaml:"level"`
+	OnLevel       float64       `yaml:"on_level"`
+	RampRate      time.Duration `yaml:"ramp_rate"`
+	LEDBrightness float64       `yaml:"led_brightness"`
+	X10Address    [2]byte       `yaml:"x10_address"`
+}
+
+// LoadDryRunState loads a dry-run state from a YAML stream.
+func LoadDryRunState(r io.Reader) (*DryRunState, error) {
+	state := &DryRunState{}
+
+	if err := yaml.NewDecoder(r).Decode(state); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// DryRunPowerLineModem simulates a PowerLine Modem.
+//
+// Every command is logged, as the frame that would have been sent to a real
+// PowerLine Modem, and applied to a simulated state instead.
+//
+// Only the light levels, the device information, the categories and the
+// All-Link DB are simulated. Raw commands, which drivers use for everything
+// else, like fan speeds or thermostat setpoints, are logged but fail with
+// ErrNotSimulated.
+type DryRunPowerLineModem struct {
+	// State is the simulated state.
+	State *DryRunState
+
+	// Log is where the commands are logged. Defaults to os.Stderr.
+	Log io.Writer
+
+	lock sync.Mutex
+}
+
+// NewDryRunPowerLineModem instantiates a new dry-run PowerLine Modem.
+//
+// The URL must be of the form dryrun://path/to/state.yml, or dryrun:// to
+// start with an empty state. The state file is never written to: changes only
+// live as long as the PowerLine Modem.
+func NewDryRunPowerLineModem(u *url.URL) (*DryRunPowerLineModem, error) {
+	m := &DryRunPowerLineModem{
+		State: &DryRunState{},
+		Log:   os.Stderr,
+	}
+
+	path := u.Host + u.Path
+
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+
+	if path != "" {
+		f, err := os.Open(path)
+
+		if err != nil {
+			return nil, fmt.Errorf("opening dry-run state: %s", err)
+		}
+
+		defer f.Close()
+
+		if m.State, err = LoadDryRunState(f); err != nil {
+			return nil, fmt.Errorf("reading dry-run state at %s: %s", path, err)
+		}
+	}
+
+	return m, nil
+}
+
+// GetIMInfo gets information about the simulated PowerLine Modem.
+func (m *DryRunPowerLineModem) GetIMInfo(ctx context.Context) (*IMInfo, error) {
+	m.logPacket(&packet{CommandCode: cmdGetIMInfo})
+
+	return &IMInfo{
+		ID: m.State.ID,
+		Category: Category{
+			MainCategory: networkBridges,
+			SubCategory:  powerlincDualBandSerial,
+		},
+	}, nil
+}
+
+// GetAllLinkDB gets the simulated All-Link DB.
+func (m *DryRunPowerLineModem) GetAllLinkDB(ctx context.Context) (records AllLinkRecordSlice, err error) {
+	m.logPacket(&packet{CommandCode: cmdGetFirstAllLinkRecord})
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, link := range m.State.Links {
+		if i > 0 {
+			m.logPacket(&packet{CommandCode: cmdGetNextAllLinkRecord})
+		}
+
+		record := AllLinkRecord{
+			Flags:    0xa2,
+			Group:    link.Group,
+			ID:       link.ID,
+			LinkData: make([]byte, 3),
+		}
+
+		if !link.Controller {
+			record.Flags |= 0x40
+		}
+
+		records = append(records, record)
+	}
+
+	sort.Stable(records)
+
+	return records, nil
+}
+
+// GetDeviceState gets the simulated on level of a device.
+func (m *DryRunPowerLineModem) GetDeviceState(ctx context.Context, identity ID) (*LightState, error) {
+	m.logMessage(newMessage(identity, commandBytesStatusRequest))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	device, err := m.getDevice(identity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LightState{
+		OnOff: device.Level > 0,
+		Level: device.Level,
+	}, nil
+}
+
+// SetDeviceState sets the simulated state of a lighting device.
+func (m *DryRunPowerLineModem) SetDeviceState(ctx context.Context, identity ID, state LightState) error {
+	m.logMessage(newMessage(identity, state.asCommandBytes()))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	device := m.getOrCreateDevice(identity)
+
+	switch state.Change {
+	case ChangeNormal, ChangeInstant:
+		if state.OnOff == LightOn {
+			device.Level = clampLevel(state.Level)
+		} else {
+			device.Level = 0
+		}
+	case ChangeStep:
+		if state.OnOff == LightOn {
+			device.Level = clampLevel(device.Level + 1.0/32)
+		} else {
+			device.Level = clampLevel(device.Level - 1.0/32)
+		}
+	}
+
+	return nil
+}
+
+// GetDeviceInfo returns the simulated information about a device.
+func (m *DryRunPowerLineModem) GetDeviceInfo(ctx context.Context, identity ID) (*DeviceInfo, error) {
+	m.logMessage(newExtendedMessage(identity, commandBytesGetDeviceInfo, [14]byte{}))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	device, err := m.getDevice(identity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	x10Address := device.X10Address
+	rampRate := device.RampRate
+	onLevel := device.OnLevel
+	ledBrightness := device.LEDBrightness
+
+	return &DeviceInfo{
+		X10Address:    &x10Address,
+		RampRate:      &rampRate,
+		OnLevel:       &onLevel,
+		LEDBrightness: &ledBrightness,
+	}, nil
+}
+
+// SetDeviceInfo sets the simulated information on device.
+func (m *DryRunPowerLineModem) SetDeviceInfo(ctx context.Context, identity ID, deviceInfo DeviceInfo) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	device := m.getOrCreateDevice(identity)
+
+	if deviceInfo.X10Address != nil {
+		m.logMessage(newExtendedMessage(identity, commandBytesSetDeviceInfo, [14]byte{1: 0x04, 2: deviceInfo.X10Address[0], 3: deviceInfo.X10Address[1]}))
+		device.X10Address = *deviceInfo.X10Address
+	}
+
+	if deviceInfo.RampRate != nil {
+		m.logMessage(newExtendedMessage(identity, commandBytesSetDeviceInfo, [14]byte{1: 0x05, 2: rampRateToByte(*deviceInfo.RampRate)}))
+		device.RampRate = *deviceInfo.RampRate
+	}
+
+	if deviceInfo.OnLevel != nil {
+		m.logMessage(newExtendedMessage(identity, commandBytesSetDeviceInfo, [14]byte{1: 0x06, 2: onLevelToByte(*deviceInfo.OnLevel)}))
+		device.OnLevel = clampLevel(*deviceInfo.OnLevel)
+	}
+
+	if deviceInfo.LEDBrightness != nil {
+		m.logMessage(newExtendedMessage(identity, commandBytesSetDeviceInfo, [14]byte{1: 0x07, 2: ledBrightnessToByte(*deviceInfo.LEDBrightness)}))
+		device.LEDBrightness = clampLevel(*deviceInfo.LEDBrightness)
+	}
+
+	return nil
+}
+
+// Beep pretends to cause a device to beep.
+func (m *DryRunPowerLineModem) Beep(ctx context.Context, identity ID) error {
+	m.logMessage(newMessage(identity, commandBytesBeep))
+
+	return nil
+}
+
+// Ping pretends to send a ping to a device.
+//
+// Only the devices of the simulated state answer.
+func (m *DryRunPowerLineModem) Ping(ctx context.Context, identity ID) (*PingResult, error) {
+	msg := newMessage(identity, commandBytesIDRequest)
+	m.logMessage(msg)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, err := m.getDevice(identity); err != nil {
+		return nil, err
+	}
+
+	return &PingResult{
+		Latency: messageRoundtripDuration(false, msg.MaxHops),
+		Hops:    msg.MaxHops,
+	}, nil
+}
+
+// GetDeviceCategory gets the simulated category of a device.
+func (m *DryRunPowerLineModem) GetDeviceCategory(ctx context.Context, identity ID) (*Category, error) {
+	m.logMessage(newMessage(identity, commandBytesIDRequest))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	device, err := m.getDevice(identity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	category := device.Category
+
+	return &category, nil
+}
+
+// SendCommand logs a raw command to a device.
+//
+// Their effects are not simulated: answering them would make their results
+// look like actual device states.
+func (m *DryRunPowerLineModem) SendCommand(ctx context.Context, identity ID, command Command) (*CommandResponse, error) {
+	m.logMessage(command.asMessage(identity))
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, err := m.getDevice(identity); err != nil {
+		return nil, err
+	}
+
+	return nil, ErrNotSimulated
+}
+
+// Monitor the simulated Insteon network, which never has any event, for as
+// long as the specified context remains valid.
+func (m *DryRunPowerLineModem) Monitor(ctx context.Context, events chan<- DeviceEvent) error {
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func (m *DryRunPowerLineModem) getDevice(identity ID) (*DryRunDevice, error) {
+	device, ok := m.State.Devices[identity]
+
+	if !ok {
+		return nil, ErrNoSuchDevice{ID: identity}
+	}
+
+	return device, nil
+}
+
+func (m *DryRunPowerLineModem) getOrCreateDevice(identity ID) *DryRunDevice {
+	if m.State.Devices == nil {
+		m.State.Devices = map[ID]*DryRunDevice{}
+	}
+
+	device, ok := m.State.Devices[identity]
+
+	if !ok {
+		device = &DryRunDevice{}
+		m.State.Devices[identity] = device
+	}
+
+	return device
+}
+
+func (m *DryRunPowerLineModem) logMessage(msg *Message) {
+	payload, _ := msg.MarshalBinary()
+
+	m.logPacket(&packet{
+		CommandCode: cmdSendStandardOrExtendedMessage,
+		Payload:     payload,
+	})
+}
+
+// logPacket logs a packet as it would be written to a PowerLine Modem, along
+// with its decoded form.
+func (m *DryRunPowerLineModem) logPacket(p *packet) {
+	b, _ := p.MarshalBinary()
+	w := m.Log
+
+	if w == nil {
+		w = os.Stderr
+	}
+
+	fmt.Fprintf(w, "dry-run > %s\t%s\n", hex.EncodeToString(b), describePacket(p))
+}
+
+// describePacket returns a human-readable description of an outgoing packet.
+func describePacket(p *packet) string {
+	switch p.CommandCode {
+	case cmdGetIMInfo:
+		return "get IM info"
+	case cmdGetFirstAllLinkRecord:
+		return "get first All-Link record"
+	case cmdGetNextAllLinkRecord:
+		return "get next All-Link record"
+	case cmdSendStandardOrExtendedMessage:
+		msg := &Message{}
+
+		if err := msg.UnmarshalBinary(p.Payload); err != nil {
+			return fmt.Sprintf("invalid message: %s", err)
+		}
+
+		parts := []string{
+			fmt.Sprintf("to %s", msg.Target),
+			fmt.Sprintf("cmd %s", hex.EncodeToString(msg.CommandBytes[:])),
+		}
+
+		if msg.Flags != 0 {
+			parts = append(parts, fmt.Sprintf("flags %s", msg.Flags))
+		}
+
+		parts = append(parts, fmt.Sprintf("hops %d/%d", msg.HopsLeft, msg.MaxHops))
+
+		if msg.IsExtended() {
+			parts = append(parts, fmt.Sprintf("data %s", hex.EncodeToString(msg.UserData[:])))
+		}
+
+		if description := describeCommandBytes(msg.CommandBytes); description != "" {
+			parts = append(parts, fmt.Sprintf("(%s)", description))
+		}
+
+		return strings.Join(parts, " ")
+	}
+
+	return fmt.Sprintf("command %02x", byte(p.CommandCode))
+}
+
+func describeCommandBytes(commandBytes [2]byte) string {
+	switch commandBytes[0] {
+	case commandBytesIDRequest[0]:
+		return "ID request"
+	case commandBytesStatusRequest[0]:
+		return "status request"
+	case commandBytesBeep[0]:
+		return "beep"
+	case commandBytesSetDeviceInfo[0]:
+		return "extended get/set"
+	}
+
+	state := &LightState{}
+
+	if err := state.UnmarshalBinary(commandBytes[:]); err != nil {
+		return ""
+	}
+
+	change, _ := state.Change.MarshalText()
+
+	if state.Change == ChangeNormal || state.Change == ChangeInstant {
+		return fmt.Sprintf("%s, %s, level %.0f%%", state.OnOff, change, state.Level*100)
+	}
+
+	return fmt.Sprintf("%s, %s", state.OnOff, change)
+}
@@ -0,0 +1,160 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+var (
+	commandBytesResetEnergy = [2]byte{0x80, 0x00}
+	commandBytesGetEnergy   = [2]byte{0x82, 0x00}
+)
+
+// EnergyMeter is a device that measures the power drawn by a load, like an
+// iMeter Solo.
+type EnergyMeter interface {
+	Device
+	GetEnergy(ctx context.Context) (*EnergyReading, error)
+	ResetEnergy(ctx context.Context) error
+}
+
+// EnergyReading represents a reading of an energy meter.
+type EnergyReading struct {
+	// Power is the instantaneous power, in watts.
+	Power float64 `json:"power"`
+
+	// Energy is the energy accumulated since the last reset, in kilowatt
+	// hours.
+	Energy float64 `json:"energy"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type energyMeter struct {
+	*BaseDevice
+}
+
+// GetEnergy returns the current reading of the energy meter.
+//
+// The meter answers with an extended message that holds the power in D7-D8
+// and the accumulated energy, as a pulse count, in D9-D12.
+func (d *energyMeter) GetEnergy(ctx context.Context) (*EnergyReading, error) {
+	value, err := d.Cached(ctx, "energy", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		command := NewCommand(commandBytesGetEnergy)
+		command.ExtendedResponse = true
+
+		response, err := plm.SendCommand(ctx, d.id, command)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Extended == nil {
+			return nil, fmt.Errorf("no energy reading received from %s", d.id)
+		}
+
+		data := response.Extended.UserData
+		power := int16(uint16(data[6])<<8 | uint16(data[7]))
+		pulses := uint32(data[8])<<24 | uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11])
+
+		return &EnergyReading{
+			Power:     float64(power),
+			Energy:    float64(pulses) * 65535 / (1000 * 60 * 60 * 60),
+			Timestamp: time.Now().UTC(),
+		}, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*EnergyReading), nil
+}
+
+// ResetEnergy resets the accumulated energy of the meter.
+func (d *energyMeter) ResetEnergy(ctx context.Context) error {
+	_, err := d.SendCommand(ctx, NewCommand(commandBytesResetEnergy))
+
+	return err
+}
+
+// Poll reads the energy meter and reports its reading as an energy event.
+func (d *energyMeter) Poll(ctx context.Context) (*DeviceEvent, error) {
+	reading, err := d.GetEnergy(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceEvent{
+		Identity: d.id,
+		Type:     "energy",
+		Attributes: map[string]interface{}{
+			"power":  reading.Power,
+			"energy": reading.Energy,
+		},
+	}, nil
+}
+
+// energyMeterDriver is the driver of energy meters.
+type energyMeterDriver struct {
+	BaseDeviceDriver
+}
+
+func (energyMeterDriver) Name() string {
+	return "energy-meter"
+}
+
+func (energyMeterDriver) NewDevice(base *BaseDevice) Device {
+	return &energyMeter{base}
+}
+
+func (energyMeterDriver) NewState() interface{} {
+	return &EnergyReading{}
+}
+
+func (energyMeterDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	energyMeter, ok := device.(EnergyMeter)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return energyMeter.GetEnergy(ctx)
+}
+
+func (energyMeterDriver) Commands() []DeviceCommand {
+	return []DeviceCommand{
+		{
+			Name:        "energy",
+			Description: "Show the power and the accumulated energy",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				energyMeter, ok := device.(EnergyMeter)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				return energyMeter.GetEnergy(ctx)
+			},
+		},
+		{
+			Name:        "reset",
+			Description: "Reset the accumulated energy",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				energyMeter, ok := device.(EnergyMeter)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				return nil, energyMeter.ResetEnergy(ctx)
+			},
+		},
+	}
+}
+
+func init() {
+	RegisterMainCategoryDeviceDriver(energyMeterDriver{}, energyManagement)
+}
@@ -1,8 +1,110 @@
 package insteon
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrCommandFailed is returned when a command failed.
 	ErrCommandFailed = errors.New("command failed")
+
+	// ErrNotSupported is returned when a device does not support an
+	// operation.
+	ErrNotSupported = errors.New("not supported by the device")
+
+	// ErrPowerLineModemClosed is returned when a PowerLine Modem is used after
+	// it was closed.
+	ErrPowerLineModemClosed = errors.New("PowerLine Modem closed")
+
+	// ErrInsteonHubBufferOverrun is returned when more data was written in the
+	// Insteon Hub buffer than could be read between two polls.
+	ErrInsteonHubBufferOverrun = errors.New("Insteon Hub buffer overrun")
+
+	// ErrNotSimulated is returned by dry-run PowerLine Modems for the
+	// commands whose effects they don't simulate.
+	ErrNotSimulated = errors.New("not simulated in dry-run mode")
 )
+
+// ErrDeviceNak is returned when a device refuses a command.
+type ErrDeviceNak struct {
+	ID     ID
+	Reason NakReason
+}
+
+// Error returns the error string.
+func (e ErrDeviceNak) Error() string {
+	return fmt.Sprintf("device %s refused the command: %s", e.ID, e.Reason)
+}
+
+// NakReason represents the reason of a device NAK.
+type NakReason byte
+
+const (
+	// NakIllegalValue indicates an illegal value in the command.
+	NakIllegalValue NakReason = 0xfb
+	// NakPreNak indicates that the device database search took too long.
+	NakPreNak NakReason = 0xfc
+	// NakChecksum indicates that the extended message checksum is invalid.
+	NakChecksum NakReason = 0xfd
+	// NakNoLoad indicates that the device detected no load.
+	NakNoLoad NakReason = 0xfe
+	// NakNotLinked indicates that the sender is not in the device database.
+	NakNotLinked NakReason = 0xff
+)
+
+func (r NakReason) String() string {
+	switch r {
+	case NakIllegalValue:
+		return "illegal value"
+	case NakPreNak:
+		return "database search took too long"
+	case NakChecksum:
+		return "invalid checksum"
+	case NakNoLoad:
+		return "no load detected"
+	case NakNotLinked:
+		return "not linked"
+	}
+
+	return fmt.Sprintf("unknown reason %02x", byte(r))
+}
+
+// MarshalText -
+func (r NakReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText -
+func (r *NakReason) UnmarshalText(b []byte) error {
+	s := string(b)
+
+	for _, reason := range []NakReason{NakIllegalValue, NakPreNak, NakChecksum, NakNoLoad, NakNotLinked} {
+		if reason.String() == s {
+			*r = reason
+			return nil
+		}
+	}
+
+	var value byte
+
+	if _, err := fmt.Sscanf(s, "unknown reason %02x", &value); err != nil {
+		return fmt.Errorf("unsupported NAK reason: %s", s)
+	}
+
+	*r = NakReason(value)
+
+	return nil
+}
+
+// ErrNoSuchCommand is returned when a device driver has no command with a
+// given name.
+type ErrNoSuchCommand struct {
+	Driver  string
+	Command string
+}
+
+// Error returns the error string.
+func (e ErrNoSuchCommand) Error() string {
+	return fmt.Sprintf("no such command for %s devices: %s", e.Driver, e.Command)
+}
@@ -0,0 +1,481 @@
+package insteon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// failoverScheme is the prefix of failover device lists.
+	failoverScheme = "failover:"
+	// failoverDeduplicationWindow is the time during which identical events
+	// received from different PowerLine Modems are considered duplicates.
+	failoverDeduplicationWindow = time.Millisecond * 500
+)
+
+// FailoverPowerLineModem implements a PowerLine Modem on top of several
+// redundant ones.
+//
+// Commands are sent to the first healthy PowerLine Modem, and fail over to
+// the next ones when it fails them. A PowerLine Modem that stops answering,
+// or that has no links while another one has, is unhealthy. Events from all
+// the PowerLine Modems are merged.
+type FailoverPowerLineModem struct {
+	PowerLineModems []PowerLineModem
+
+	// HealthCheckInterval is the interval between two health checks.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout is the time given to a PowerLine Modem to answer a
+	// health check.
+	HealthCheckTimeout time.Duration
+
+	once       sync.Once
+	ctx        context.Context
+	cancel     func()
+	lock       sync.Mutex
+	healthy    []bool
+	demoted    []bool
+	links      []bool
+	linksKnown []bool
+}
+
+// NewFailoverPowerLineModem instantiates a new failover PowerLine Modem.
+//
+// The PowerLine Modems are used in the specified order of preference.
+func NewFailoverPowerLineModem(powerLineModems ...PowerLineModem) *FailoverPowerLineModem {
+	return &FailoverPowerLineModem{
+		PowerLineModems: powerLineModems,
+	}
+}
+
+// newFailoverPowerLineModemFromDevices instantiates a failover PowerLine Modem
+// from a comma-separated list of devices.
+func newFailoverPowerLineModemFromDevices(devices string) (*FailoverPowerLineModem, error) {
+	var powerLineModems []PowerLineModem
+
+	for _, device := range strings.Split(devices, ",") {
+		plm, err := NewPowerLineModem(device)
+
+		if err != nil {
+			for _, plm := range powerLineModems {
+				if closer, ok := plm.(io.Closer); ok {
+					closer.Close()
+				}
+			}
+
+			return nil, fmt.Errorf("opening %s: %s", device, err)
+		}
+
+		powerLineModems = append(powerLineModems, plm)
+	}
+
+	return NewFailoverPowerLineModem(powerLineModems...), nil
+}
+
+// Close stops the health checks and closes the underlying PowerLine Modems.
+func (m *FailoverPowerLineModem) Close() error {
+	m.init()
+	m.cancel()
+
+	var err error
+
+	for _, plm := range m.PowerLineModems {
+		if closer, ok := plm.(io.Closer); ok {
+			if cerr := closer.Close(); cerr != nil {
+				err = cerr
+			}
+		}
+	}
+
+	return err
+}
+
+// Healthy returns the health status of each PowerLine Modem.
+func (m *FailoverPowerLineModem) Healthy() []bool {
+	m.init()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := make([]bool, len(m.healthy))
+	copy(result, m.healthy)
+
+	return result
+}
+
+// GetIMInfo gets information about the active PowerLine Modem.
+func (m *FailoverPowerLineModem) GetIMInfo(ctx context.Context) (imInfo *IMInfo, err error) {
+	err = m.do(ctx, func(plm PowerLineModem) (err error) {
+		imInfo, err = plm.GetIMInfo(ctx)
+		return
+	})
+
+	return
+}
+
+// GetAllLinkDB gets the All-Link DB of the active PowerLine Modem.
+func (m *FailoverPowerLineModem) GetAllLinkDB(ctx context.Context) (records AllLinkRecordSlice, err error) {
+	err = m.do(ctx, func(plm PowerLineModem) (err error) {
+		records, err = plm.GetAllLinkDB(ctx)
+		return
+	})
+
+	return
+}
+
+// GetDeviceState gets the on level of a device.
+func (m *FailoverPowerLineModem) GetDeviceState(ctx context.Context, identity ID) (state *LightState, err error) {
+	err = m.do(ctx, func(plm PowerLineModem) (err error) {
+		state, err = plm.GetDeviceState(ctx, identity)
+		return
+	})
+
+	return
+}
+
+// SetDeviceState sets the state of a lighting device.
+func (m *FailoverPowerLineModem) SetDeviceState(ctx context.Context, identity ID, state LightState) error {
+	return m.do(ctx, func(plm PowerLineModem) error {
+		return plm.SetDeviceState(ctx, identity, state)
+	})
+}
+
+// GetDeviceInfo returns the information about a device.
+func (m *FailoverPowerLineModem) GetDeviceInfo(ctx context.Context, identity ID) (deviceInfo *DeviceInfo, err error) {
+	err = m.do(ctx, func(plm PowerLineModem) (err error) {
+		deviceInfo, err = plm.GetDeviceInfo(ctx, identity)
+		return
+	})
+
+	return
+}
+
+// SetDeviceInfo sets the information on device.
+func (m *FailoverPowerLineModem) SetDeviceInfo(ctx context.Context, identity ID, deviceInfo DeviceInfo) error {
+	return m.do(ctx, func(plm PowerLineModem) error {
+		return plm.SetDeviceInfo(ctx, identity, deviceInfo)
+	})
+}
+
+// Beep causes a device to beep.
+func (m *FailoverPowerLineModem) Beep(ctx context.Context, identity ID) error {
+	return m.do(ctx, func(plm PowerLineModem) error {
+		return plm.Beep(ctx, identity)
+	})
+}
+
+// Ping sends a ping to a device.
+func (m *FailoverPowerLineModem) Ping(ctx context.Context, identity ID) (result *PingResult, err error) {
+	err = m.do(ctx, func(plm PowerLineModem) (err error) {
+		result, err = plm.Ping(ctx, identity)
+		return
+	})
+
+	return
+}
+
+// GetDeviceCategory gets the category of a device.
+func (m *FailoverPowerLineModem) GetDeviceCategory(ctx context.Context, identity ID) (category *Category, err error) {
+	err = m.do(ctx, func(plm PowerLineModem) (err error) {
+		category, err = plm.GetDeviceCategory(ctx, identity)
+		return
+	})
+
+	return
+}
+
+// SendCommand sends a raw command to a device.
+func (m *FailoverPowerLineModem) SendCommand(ctx context.Context, identity ID, command Command) (response *CommandResponse, err error) {
+	err = m.do(ctx, func(plm PowerLineModem) (err error) {
+		response, err = plm.SendCommand(ctx, identity, command)
+		return
+	})
+
+	return
+}
+
+// Monitor the Insteon network through all the PowerLine Modems for as long
+// as the specified context remains valid.
+//
+// Events seen by several PowerLine Modems are only pushed once, but the ones
+// repeated on the same PowerLine Modem, like successive button presses, are
+// all pushed.
+func (m *FailoverPowerLineModem) Monitor(ctx context.Context, events chan<- DeviceEvent) error {
+	m.init()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	merged := make(chan failoverEvent, 10)
+	errs := make(chan error, len(m.PowerLineModems))
+
+	for i, plm := range m.PowerLineModems {
+		ch := make(chan DeviceEvent, 10)
+
+		go func(plm PowerLineModem) {
+			errs <- plm.Monitor(ctx, ch)
+		}(plm)
+
+		go func(i int) {
+			for {
+				select {
+				case event := <-ch:
+					select {
+					case merged <- failoverEvent{Index: i, DeviceEvent: event}:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i)
+	}
+
+	seen := map[string]failoverEvent{}
+	running := len(m.PowerLineModems)
+	var lastErr error
+
+	for running > 0 {
+		select {
+		case event := <-merged:
+			now := time.Now().UTC()
+			key := failoverDeduplicationKey(event.DeviceEvent)
+
+			for k, e := range seen {
+				if now.Sub(e.Timestamp) > failoverDeduplicationWindow {
+					delete(seen, k)
+				}
+			}
+
+			// The copy of the frame seen by another PowerLine Modem.
+			if e, ok := seen[key]; ok && e.Index != event.Index {
+				delete(seen, key)
+				continue
+			}
+
+			event.Timestamp = now
+			seen[key] = event
+
+			select {
+			case events <- event.DeviceEvent:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case err := <-errs:
+			running--
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			lastErr = err
+		}
+	}
+
+	return fmt.Errorf("all PowerLine Modems stopped monitoring: %s", lastErr)
+}
+
+// failoverEvent is an event received from one of the PowerLine Modems.
+type failoverEvent struct {
+	DeviceEvent
+
+	Index     int
+	Timestamp time.Time
+}
+
+// failoverDeduplicationKey returns the key under which an event is
+// deduplicated.
+//
+// Events received with a message are deduplicated on the message, as the
+// other fields depend on how it was decoded.
+func failoverDeduplicationKey(event DeviceEvent) string {
+	if msg := event.Message; msg != nil {
+		return fmt.Sprintf("%s>%s:%x:%x", msg.Source, msg.Target, msg.CommandBytes, msg.UserData)
+	}
+
+	return fmt.Sprintf("%s:%v:%v:%d", event.Identity, event.OnOff, event.Change, event.Group)
+}
+
+func (m *FailoverPowerLineModem) init() {
+	m.once.Do(func() {
+		if m.HealthCheckInterval == 0 {
+			m.HealthCheckInterval = time.Second * 30
+		}
+
+		if m.HealthCheckTimeout == 0 {
+			m.HealthCheckTimeout = time.Second * 5
+		}
+
+		m.ctx, m.cancel = context.WithCancel(context.Background())
+		m.healthy = make([]bool, len(m.PowerLineModems))
+		m.demoted = make([]bool, len(m.PowerLineModems))
+		m.links = make([]bool, len(m.PowerLineModems))
+		m.linksKnown = make([]bool, len(m.PowerLineModems))
+
+		// The first check is done right away, so that a PowerLine Modem that
+		// was reset is never used.
+		m.checkHealth(m.ctx)
+
+		go m.healthCheckLoop(m.ctx)
+	})
+}
+
+// do executes a function on the healthy PowerLine Modems, in order, until
+// one succeeds.
+//
+// Unhealthy PowerLine Modems are tried last, in case they have recovered. The
+// PowerLine Modems that failed a command another one succeeded remain
+// unhealthy until they succeed one again, or pass a health check.
+func (m *FailoverPowerLineModem) do(ctx context.Context, fn func(PowerLineModem) error) error {
+	m.init()
+
+	var err error
+	var failed []int
+
+	for _, i := range m.order() {
+		if err = fn(m.PowerLineModems[i]); err == nil {
+			m.setDemoted(i, false)
+
+			for _, j := range failed {
+				m.setDemoted(j, true)
+			}
+
+			return nil
+		}
+
+		// The command reached the device, which refused it: another modem
+		// won't do better.
+		var nak ErrDeviceNak
+
+		if errors.As(err, &nak) || ctx.Err() != nil {
+			return err
+		}
+
+		failed = append(failed, i)
+	}
+
+	return err
+}
+
+func (m *FailoverPowerLineModem) order() []int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var healthy, unhealthy []int
+
+	for i, ok := range m.healthy {
+		if ok {
+			healthy = append(healthy, i)
+		} else {
+			unhealthy = append(unhealthy, i)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (m *FailoverPowerLineModem) setDemoted(i int, demoted bool) {
+	m.lock.Lock()
+	m.demoted[i] = demoted
+	m.healthy[i] = m.healthy[i] && !demoted
+	m.lock.Unlock()
+}
+
+func (m *FailoverPowerLineModem) healthCheckLoop(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(m.HealthCheckInterval):
+		case <-ctx.Done():
+			return
+		}
+
+		m.checkHealth(ctx)
+	}
+}
+
+// checkHealth checks all the PowerLine Modems.
+func (m *FailoverPowerLineModem) checkHealth(ctx context.Context) {
+	answers := make([]bool, len(m.PowerLineModems))
+
+	for i, plm := range m.PowerLineModems {
+		answers[i] = m.healthCheck(ctx, i, plm)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	hasLinks := false
+
+	for _, links := range m.links {
+		hasLinks = hasLinks || links
+	}
+
+	for i, ok := range answers {
+		// A PowerLine Modem with no links while another one has some was
+		// probably reset.
+		if hasLinks && !m.links[i] {
+			ok = false
+		}
+
+		// A PowerLine Modem that passes the check has recovered from the
+		// commands it failed.
+		m.healthy[i] = ok
+		m.demoted[i] = m.demoted[i] && !ok
+	}
+}
+
+// healthCheck checks that a PowerLine Modem answers, and records whether it
+// has links.
+func (m *FailoverPowerLineModem) healthCheck(ctx context.Context, i int, plm PowerLineModem) bool {
+	ctx, cancel := context.WithTimeout(ctx, m.HealthCheckTimeout)
+	defer cancel()
+
+	if _, err := plm.GetIMInfo(ctx); err != nil {
+		return false
+	}
+
+	links, err := m.hasLinks(ctx, i, plm)
+
+	if err != nil {
+		return false
+	}
+
+	m.lock.Lock()
+	m.links[i] = links
+	m.linksKnown[i] = true
+	m.lock.Unlock()
+
+	return true
+}
+
+// hasLinks tells whether a PowerLine Modem has links.
+//
+// Only the first record is read, when the PowerLine Modem supports it.
+// Otherwise, the whole All-Link DB is read once and the result is kept, to
+// avoid loading the powerline at every check.
+func (m *FailoverPowerLineModem) hasLinks(ctx context.Context, i int, plm PowerLineModem) (bool, error) {
+	if plm, ok := plm.(interface {
+		hasLinks(ctx context.Context) (bool, error)
+	}); ok {
+		return plm.hasLinks(ctx)
+	}
+
+	m.lock.Lock()
+	links, known := m.links[i], m.linksKnown[i]
+	m.lock.Unlock()
+
+	if known {
+		return links, nil
+	}
+
+	records, err := plm.GetAllLinkDB(ctx)
+
+	return len(records) > 0, err
+}
@@ -0,0 +1,202 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	commandBytesGetFanSpeed = [2]byte{0x19, 0x03}
+)
+
+// Fan is a FanLinc: a fan with a dimmable light.
+//
+// The light is on group 1 and the fan on group 2.
+type Fan interface {
+	Device
+	Light() Dimmer
+	GetSpeed(ctx context.Context) (FanSpeed, error)
+	SetSpeed(ctx context.Context, speed FanSpeed) error
+}
+
+// FanSpeed is the speed of a fan.
+type FanSpeed int
+
+const (
+	// FanOff indicates a stopped fan.
+	FanOff FanSpeed = iota
+	// FanLow indicates a fan at low speed.
+	FanLow
+	// FanMedium indicates a fan at medium speed.
+	FanMedium
+	// FanHigh indicates a fan at high speed.
+	FanHigh
+)
+
+func (s FanSpeed) String() string {
+	switch s {
+	case FanLow:
+		return "low"
+	case FanMedium:
+		return "medium"
+	case FanHigh:
+		return "high"
+	default:
+		return "off"
+	}
+}
+
+// UnmarshalText -
+func (s *FanSpeed) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "off":
+		*s = FanOff
+	case "low":
+		*s = FanLow
+	case "medium":
+		*s = FanMedium
+	case "high":
+		*s = FanHigh
+	default:
+		return fmt.Errorf("unsupported fan speed: %s", string(b))
+	}
+
+	return nil
+}
+
+// MarshalText -
+func (s FanSpeed) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// FanState represents the state of the fan of a FanLinc.
+type FanState struct {
+	Speed FanSpeed `json:"speed"`
+}
+
+func byteToFanSpeed(b byte) FanSpeed {
+	switch {
+	case b == 0x00:
+		return FanOff
+	case b <= 0x7f:
+		return FanLow
+	case b <= 0xbf:
+		return FanMedium
+	default:
+		return FanHigh
+	}
+}
+
+func fanSpeedToByte(speed FanSpeed) byte {
+	switch speed {
+	case FanLow:
+		return 0x55
+	case FanMedium:
+		return 0xaa
+	case FanHigh:
+		return 0xff
+	default:
+		return 0x00
+	}
+}
+
+type fan struct {
+	*BaseDevice
+}
+
+// Light returns the light of the fan.
+func (d *fan) Light() Dimmer {
+	return &dimmer{relay{d.BaseDevice}}
+}
+
+// GetSpeed returns the speed of the fan.
+func (d *fan) GetSpeed(ctx context.Context) (FanSpeed, error) {
+	value, err := d.Cached(ctx, "speed", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		response, err := plm.SendCommand(ctx, d.id, NewCommand(commandBytesGetFanSpeed))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return byteToFanSpeed(response.Ack.CommandBytes[1]), nil
+	})
+
+	if err != nil {
+		return FanOff, err
+	}
+
+	return value.(FanSpeed), nil
+}
+
+// SetSpeed sets the speed of the fan.
+//
+// The speed is set with an extended on command that has the group of the
+// fan in its user data.
+func (d *fan) SetSpeed(ctx context.Context, speed FanSpeed) error {
+	commandBytes := [2]byte{0x11, fanSpeedToByte(speed)}
+
+	if speed == FanOff {
+		commandBytes[0] = 0x13
+	}
+
+	_, err := d.SendCommand(ctx, NewExtendedCommand(commandBytes, [14]byte{0x02}))
+
+	return err
+}
+
+// fanDriver is the driver of FanLincs.
+//
+// The state and information are the ones of the light.
+type fanDriver struct {
+	lightDriver
+}
+
+func (fanDriver) Name() string {
+	return "fan"
+}
+
+func (fanDriver) NewDevice(base *BaseDevice) Device {
+	return &fan{base}
+}
+
+func (fanDriver) Commands() []DeviceCommand {
+	return append([]DeviceCommand{
+		{
+			Name:        "speed",
+			Usage:       "[off|low|medium|high]",
+			Description: "Show or set the speed of the fan",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				fan, ok := device.(Fan)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				switch len(args) {
+				case 0:
+					speed, err := fan.GetSpeed(ctx)
+
+					if err != nil {
+						return nil, err
+					}
+
+					return FanState{Speed: speed}, nil
+				case 1:
+					var speed FanSpeed
+
+					if err := speed.UnmarshalText([]byte(args[0])); err != nil {
+						return nil, err
+					}
+
+					return nil, fan.SetSpeed(ctx, speed)
+				}
+
+				return nil, fmt.Errorf("expected at most one argument but got %d", len(args))
+			},
+		},
+	}, dimmerCommands...)
+}
+
+func init() {
+	RegisterDeviceDriver(fanDriver{}, Category{dimmableLightingControl, fanLinc})
+}
@@ -0,0 +1,319 @@
+package insteon
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FaultScenario describes the faults to inject in a PowerLine Modem stream.
+//
+// Rates are probabilities, in the [0, 1] range, applied to every frame read
+// from or written to the device.
+type FaultScenario struct {
+	// Seed makes the scenario reproducible.
+	Seed int64
+	// DropRate is the rate of frames that are dropped.
+	DropRate float64
+	// DropByteRate is the rate of frames that lose one of their bytes.
+	DropByteRate float64
+	// DuplicateRate is the rate of frames that are received twice.
+	DuplicateRate float64
+	// DelayRate is the rate of frames that are delayed by Delay.
+	DelayRate float64
+	Delay     time.Duration
+	// ReorderRate is the rate of frames that are received after the next
+	// one.
+	ReorderRate float64
+	// CorruptRate is the rate of frames that have one of their bytes
+	// corrupted.
+	CorruptRate float64
+	// NakRate is the rate of written frames that get a spurious NAK instead
+	// of being sent.
+	NakRate float64
+	// DisconnectRate is the rate of frames that cause a disconnection, after
+	// which the PowerLine Modem reopens the device.
+	DisconnectRate float64
+}
+
+// ParseFaultScenario parses a fault scenario of the form
+// "seed=42,drop=0.01,delay=0.1:200ms,...".
+//
+// The supported keys are seed, drop, dropbyte, dup, delay (as
+// rate:duration), reorder, corrupt, nak and disconnect.
+func ParseFaultScenario(s string) (scenario FaultScenario, err error) {
+	scenario.Seed = 1
+
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+
+		if len(kv) != 2 {
+			return scenario, fmt.Errorf("invalid fault `%s`: expected key=value", part)
+		}
+
+		key, value := kv[0], kv[1]
+
+		switch key {
+		case "seed":
+			scenario.Seed, err = strconv.ParseInt(value, 10, 64)
+		case "drop":
+			scenario.DropRate, err = parseRate(value)
+		case "dropbyte":
+			scenario.DropByteRate, err = parseRate(value)
+		case "dup":
+			scenario.DuplicateRate, err = parseRate(value)
+		case "delay":
+			rateAndDelay := strings.SplitN(value, ":", 2)
+
+			if scenario.DelayRate, err = parseRate(rateAndDelay[0]); err == nil {
+				scenario.Delay = time.Millisecond * 100
+
+				if len(rateAndDelay) == 2 {
+					scenario.Delay, err = time.ParseDuration(rateAndDelay[1])
+				}
+			}
+		case "reorder":
+			scenario.ReorderRate, err = parseRate(value)
+		case "corrupt":
+			scenario.CorruptRate, err = parseRate(value)
+		case "nak":
+			scenario.NakRate, err = parseRate(value)
+		case "disconnect":
+			scenario.DisconnectRate, err = parseRate(value)
+		default:
+			return scenario, fmt.Errorf("unknown fault `%s`", key)
+		}
+
+		if err != nil {
+			return scenario, fmt.Errorf("invalid value for fault `%s`: %s", key, err)
+		}
+	}
+
+	return scenario, nil
+}
+
+func parseRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(s, 64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate must be in the [0, 1] range")
+	}
+
+	return rate, nil
+}
+
+type faultChunk struct {
+	data []byte
+	err  error
+}
+
+// faultInjectionReadWriteCloser injects faults in a PowerLine Modem stream,
+// according to a scenario.
+type faultInjectionReadWriteCloser struct {
+	io.ReadWriteCloser
+	Scenario FaultScenario
+
+	once    sync.Once
+	lock    sync.Mutex
+	chunks  chan faultChunk
+	pending []byte
+	closed  chan struct{}
+
+	// The frames read and written get their faults from distinct sources,
+	// so that the scenario doesn't depend on how they interleave.
+	readRand  *rand.Rand
+	writeRand *rand.Rand
+}
+
+func (d *faultInjectionReadWriteCloser) Read(buf []byte) (int, error) {
+	d.init()
+
+	if len(d.pending) == 0 {
+		chunk, ok := <-d.chunks
+
+		if !ok {
+			return 0, io.EOF
+		}
+
+		if chunk.err != nil {
+			return 0, chunk.err
+		}
+
+		d.pending = chunk.data
+	}
+
+	n := copy(buf, d.pending)
+	d.pending = d.pending[n:]
+
+	return n, nil
+}
+
+// Write to the device.
+func (d *faultInjectionReadWriteCloser) Write(buf []byte) (int, error) {
+	d.init()
+
+	if d.happens(d.writeRand, d.Scenario.DisconnectRate) {
+		d.ReadWriteCloser.Close()
+
+		return 0, io.ErrClosedPipe
+	}
+
+	// Answer with a NAK as the PLM would do if it were busy.
+	if isEchoedFrame(buf) && d.happens(d.writeRand, d.Scenario.NakRate) {
+		nak := make([]byte, len(buf)+1)
+		copy(nak, buf)
+		nak[len(buf)] = messageNak
+
+		select {
+		case d.chunks <- faultChunk{data: nak}:
+		case <-d.closed:
+		}
+
+		return len(buf), nil
+	}
+
+	return d.ReadWriteCloser.Write(buf)
+}
+
+// Close the device.
+func (d *faultInjectionReadWriteCloser) Close() error {
+	d.init()
+
+	select {
+	case <-d.closed:
+	default:
+		close(d.closed)
+	}
+
+	return d.ReadWriteCloser.Close()
+}
+
+func (d *faultInjectionReadWriteCloser) init() {
+	d.once.Do(func() {
+		d.readRand = rand.New(rand.NewSource(d.Scenario.Seed))
+		d.writeRand = rand.New(rand.NewSource(^d.Scenario.Seed))
+		d.chunks = make(chan faultChunk, 16)
+		d.closed = make(chan struct{})
+
+		go d.readLoop()
+	})
+}
+
+// readLoop reads whole frames from the device and applies the faults to
+// them.
+func (d *faultInjectionReadWriteCloser) readLoop() {
+	r := newPacketReader(d.ReadWriteCloser)
+	var held []byte
+
+	for {
+		frame, err := r.Read()
+
+		if err != nil {
+			d.push(faultChunk{err: err})
+			return
+		}
+
+		if d.happens(d.readRand, d.Scenario.DisconnectRate) {
+			d.ReadWriteCloser.Close()
+			d.push(faultChunk{err: io.ErrUnexpectedEOF})
+
+			return
+		}
+
+		if d.happens(d.readRand, d.Scenario.DropRate) {
+			continue
+		}
+
+		if d.happens(d.readRand, d.Scenario.DropByteRate) {
+			i := d.intn(d.readRand, len(frame))
+			frame = append(frame[:i:i], frame[i+1:]...)
+		}
+
+		if d.happens(d.readRand, d.Scenario.CorruptRate) {
+			frame[d.intn(d.readRand, len(frame))] ^= byte(1 + d.intn(d.readRand, 0xff))
+		}
+
+		if d.happens(d.readRand, d.Scenario.DelayRate) {
+			select {
+			case <-time.After(d.Scenario.Delay):
+			case <-d.closed:
+				return
+			}
+		}
+
+		if held == nil && d.happens(d.readRand, d.Scenario.ReorderRate) {
+			held = frame
+			continue
+		}
+
+		if !d.push(faultChunk{data: frame}) {
+			return
+		}
+
+		if d.happens(d.readRand, d.Scenario.DuplicateRate) {
+			if !d.push(faultChunk{data: frame}) {
+				return
+			}
+		}
+
+		if held != nil {
+			if !d.push(faultChunk{data: held}) {
+				return
+			}
+
+			held = nil
+		}
+	}
+}
+
+// isEchoedFrame returns whether a frame sent by the host is echoed by the
+// PLM, followed by an ACK or a NAK.
+func isEchoedFrame(buf []byte) bool {
+	if len(buf) < 2 || buf[0] != messageStart {
+		return false
+	}
+
+	commandCode := CommandCode(buf[1])
+	size, ok := hostPacketSizes[commandCode]
+
+	return ok && packetSizes[commandCode] == size+1
+}
+
+func (d *faultInjectionReadWriteCloser) push(chunk faultChunk) bool {
+	select {
+	case d.chunks <- chunk:
+		return true
+	case <-d.closed:
+		return false
+	}
+}
+
+func (d *faultInjectionReadWriteCloser) happens(r *rand.Rand, rate float64) bool {
+	if rate == 0 {
+		return false
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return r.Float64() < rate
+}
+
+func (d *faultInjectionReadWriteCloser) intn(r *rand.Rand, n int) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return r.Intn(n)
+}
@@ -0,0 +1,148 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultTravelTime is the travel time of devices that have none configured.
+const defaultTravelTime = time.Second * 15
+
+// GarageDoor is a garage door driven by an I/O Linc: the relay triggers the
+// door opener and the sensor tells whether the door is closed.
+//
+// The sensor is expected to be on when the door is closed, as with the
+// garage door kit, and the relay to be in a momentary mode. As the sensor
+// only detects closed doors, the door is reported as opening or closing
+// for its travel time after it is triggered.
+type GarageDoor interface {
+	Device
+	GetDoorState(ctx context.Context) (DoorState, error)
+	OpenDoor(ctx context.Context) error
+	CloseDoor(ctx context.Context) error
+}
+
+// DoorState is the state of a door.
+type DoorState int
+
+const (
+	// DoorClosed indicates a closed door.
+	DoorClosed DoorState = iota
+	// DoorOpen indicates an open door.
+	DoorOpen
+	// DoorOpening indicates an opening door.
+	DoorOpening
+	// DoorClosing indicates a closing door.
+	DoorClosing
+)
+
+var doorStates = []string{"closed", "open", "opening", "closing"}
+
+func (s DoorState) String() string {
+	if int(s) < len(doorStates) {
+		return doorStates[s]
+	}
+
+	return fmt.Sprintf("unknown door state %d", int(s))
+}
+
+// UnmarshalText -
+func (s *DoorState) UnmarshalText(b []byte) error {
+	for i, state := range doorStates {
+		if state == string(b) {
+			*s = DoorState(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported door state: %s", string(b))
+}
+
+// MarshalText -
+func (s DoorState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// GarageDoorState represents the state of a garage door.
+type GarageDoorState struct {
+	State DoorState `json:"state"`
+}
+
+// GetDoorState returns the state of the garage door.
+func (d *ioLinc) GetDoorState(ctx context.Context) (DoorState, error) {
+	closed, err := d.GetSensor(ctx)
+
+	if err != nil {
+		return DoorClosed, err
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	moving := time.Since(d.lastTrigger) < d.travelTime()
+
+	switch {
+	case closed:
+		return DoorClosed, nil
+	case moving && d.triggeredFrom == DoorClosed:
+		return DoorOpening, nil
+	case moving:
+		return DoorClosing, nil
+	}
+
+	return DoorOpen, nil
+}
+
+// OpenDoor opens the garage door, unless it is already open or opening.
+func (d *ioLinc) OpenDoor(ctx context.Context) error {
+	return d.moveDoor(ctx, DoorClosed, DoorClosing)
+}
+
+// CloseDoor closes the garage door, unless it is already closed or closing.
+func (d *ioLinc) CloseDoor(ctx context.Context) error {
+	return d.moveDoor(ctx, DoorOpen, DoorOpening)
+}
+
+// moveDoor triggers the door opener if the door is in one of the specified
+// states.
+func (d *ioLinc) moveDoor(ctx context.Context, states ...DoorState) error {
+	state, err := d.GetDoorState(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, s := range states {
+		if s == state {
+			if err := d.On(ctx); err != nil {
+				return err
+			}
+
+			d.lock.Lock()
+			d.lastTrigger = time.Now()
+
+			if state == DoorClosed {
+				d.triggeredFrom = DoorClosed
+			} else {
+				d.triggeredFrom = DoorOpen
+			}
+
+			d.lock.Unlock()
+
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// travelTime returns the configured travel time of a device, used by
+// garage doors and window coverings.
+func (d *BaseDevice) travelTime() time.Duration {
+	if config := d.Config(); config != nil && config.TravelTime > 0 {
+		return config.TravelTime
+	}
+
+	return defaultTravelTime
+}
@@ -0,0 +1,224 @@
+package insteon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// defaultHops is the number of hops used for devices we know nothing
+	// about.
+	defaultHops = 2
+	// maxHops is the maximum number of hops supported by Insteon.
+	maxHops = 3
+	// hopsHistorySize is the number of recent attempts used to choose the
+	// number of hops of a device.
+	hopsHistorySize = 8
+)
+
+// DeviceLinkStatistics contains the link statistics of a device.
+type DeviceLinkStatistics struct {
+	Sent     uint64 `json:"sent"`
+	Acked    uint64 `json:"acked"`
+	HopsUsed []int  `json:"hops_used"`
+}
+
+// SuccessRate returns the ratio of acknowledged messages.
+func (s DeviceLinkStatistics) SuccessRate() float64 {
+	if s.Sent == 0 {
+		return 1
+	}
+
+	return float64(s.Acked) / float64(s.Sent)
+}
+
+// Hops returns the number of hops to use to reach the device.
+func (s DeviceLinkStatistics) Hops() int {
+	if len(s.HopsUsed) == 0 {
+		return defaultHops
+	}
+
+	hops := 0
+	failures := 0
+
+	for _, used := range s.HopsUsed {
+		if used < 0 {
+			failures++
+		} else if used > hops {
+			hops = used
+		}
+	}
+
+	// Without any recent success, the hops the device needs are unknown.
+	if failures == len(s.HopsUsed) {
+		hops = defaultHops
+	}
+
+	// Too many recent failures: give the messages some more room.
+	if failures*4 > len(s.HopsUsed) {
+		hops++
+	}
+
+	if hops > maxHops {
+		return maxHops
+	}
+
+	return hops
+}
+
+// PowerLineModemHops, if set, is the hops tracker of the serial PowerLine
+// Modems that are instantiated without one.
+//
+// It is not set by default, so that nothing is persisted unless asked.
+var PowerLineModemHops *HopsTracker
+
+// HopsTracker learns the number of hops needed to reach each device.
+type HopsTracker struct {
+	// Path is the file where the learned values are persisted by Save. If
+	// empty, the values are not persisted.
+	Path string
+
+	lock    sync.Mutex
+	devices map[ID]*DeviceLinkStatistics
+	changed bool
+}
+
+// LoadHopsTracker loads a hops tracker from the specified path.
+//
+// A missing file results in an empty tracker.
+func LoadHopsTracker(path string) (*HopsTracker, error) {
+	t := &HopsTracker{
+		Path:    path,
+		devices: map[ID]*DeviceLinkStatistics{},
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &t.devices); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Hops returns the number of hops to use for a device.
+func (t *HopsTracker) Hops(id ID) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if stats := t.devices[id]; stats != nil {
+		return stats.Hops()
+	}
+
+	return defaultHops
+}
+
+// Statistics returns the link statistics of all the known devices.
+func (t *HopsTracker) Statistics() map[ID]DeviceLinkStatistics {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := make(map[ID]DeviceLinkStatistics, len(t.devices))
+
+	for id, stats := range t.devices {
+		result[id] = *stats
+	}
+
+	return result
+}
+
+// Record the outcome of a message sent to a device.
+//
+// hopsUsed is the number of hops the acknowledgment needed to come back, and
+// is ignored if the message was not acknowledged.
+func (t *HopsTracker) Record(id ID, acked bool, hopsUsed int) {
+	t.lock.Lock()
+
+	if t.devices == nil {
+		t.devices = map[ID]*DeviceLinkStatistics{}
+	}
+
+	stats := t.devices[id]
+
+	if stats == nil {
+		stats = &DeviceLinkStatistics{}
+		t.devices[id] = stats
+	}
+
+	hops := stats.Hops()
+	stats.Sent++
+
+	if acked {
+		stats.Acked++
+	} else {
+		hopsUsed = -1
+	}
+
+	stats.HopsUsed = append(stats.HopsUsed, hopsUsed)
+
+	if len(stats.HopsUsed) > hopsHistorySize {
+		stats.HopsUsed = stats.HopsUsed[len(stats.HopsUsed)-hopsHistorySize:]
+	}
+
+	if hops != stats.Hops() {
+		t.changed = true
+	}
+
+	t.lock.Unlock()
+}
+
+// Save persists the learned values, if they changed since they were loaded
+// or last saved.
+func (t *HopsTracker) Save() error {
+	if t == nil || t.Path == "" {
+		return nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.changed {
+		return nil
+	}
+
+	data, err := json.Marshal(t.devices)
+
+	if err != nil {
+		return fmt.Errorf("marshalling hops: %s", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(t.Path), 0755); err != nil {
+		return fmt.Errorf("creating hops directory: %s", err)
+	}
+
+	if err = ioutil.WriteFile(t.Path, data, 0644); err != nil {
+		return fmt.Errorf("writing hops: %s", err)
+	}
+
+	t.changed = false
+
+	return nil
+}
+
+// LoadDefaultHopsTracker loads the hops tracker of the current user.
+func LoadDefaultHopsTracker() (*HopsTracker, error) {
+	path, err := getUserStatePath("hops.json")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return LoadHopsTracker(path)
+}
@@ -78,7 +78,7 @@ func (m *HTTPPowerLineModem) GetDeviceInfo(ctx context.Context, identity ID) (de
 func (m *HTTPPowerLineModem) SetDeviceInfo(ctx context.Context, identity ID, deviceInfo DeviceInfo) error {
 	url := fmt.Sprintf("/plm/device/%s/info", identity)
 
-	return m.do(ctx, http.MethodPut, url, nil, deviceInfo)
+	return m.do(ctx, http.MethodPut, url, deviceInfo, nil)
 }
 
 // Beep causes a device to beep.
@@ -88,11 +88,82 @@ func (m *HTTPPowerLineModem) Beep(ctx context.Context, identity ID) error {
 	return m.do(ctx, http.MethodPost, url, nil, nil)
 }
 
+// Ping sends a ping to a device.
+func (m *HTTPPowerLineModem) Ping(ctx context.Context, identity ID) (result *PingResult, err error) {
+	url := fmt.Sprintf("/plm/device/%s/ping", identity)
+	result = &PingResult{}
+	err = m.do(ctx, http.MethodPost, url, nil, result)
+
+	return
+}
+
+// GetDeviceCategory gets the category of a device.
+func (m *HTTPPowerLineModem) GetDeviceCategory(ctx context.Context, identity ID) (category *Category, err error) {
+	url := fmt.Sprintf("/plm/device/%s/category", identity)
+	category = &Category{}
+	err = m.do(ctx, http.MethodGet, url, nil, category)
+
+	return
+}
+
+// SendCommand sends a raw command to a device.
+func (m *HTTPPowerLineModem) SendCommand(ctx context.Context, identity ID, command Command) (response *CommandResponse, err error) {
+	url := fmt.Sprintf("/plm/device/%s/command", identity)
+	response = &CommandResponse{}
+	err = m.do(ctx, http.MethodPost, url, command, response)
+
+	return
+}
+
 // Monitor the Insteon network for changes for as long as the specified context remains valid.
 //
 // All events are pushed to the specified events channel.
 func (m *HTTPPowerLineModem) Monitor(ctx context.Context, events chan<- DeviceEvent) error {
-	return fmt.Errorf("not implemented")
+	m.init()
+
+	u := *m.URL
+	u.Path = "/plm/monitor"
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+
+	if err != nil {
+		return fmt.Errorf("creating new request: %s", err)
+	}
+
+	req = req.WithContext(ctx)
+
+	resp, err := m.Client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("executing request: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	// The events are streamed as a sequence of JSON values.
+	decoder := json.NewDecoder(resp.Body)
+
+	for {
+		var event DeviceEvent
+
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return fmt.Errorf("decoding event: %s", err)
+		}
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 func (m *HTTPPowerLineModem) init() {
@@ -7,6 +7,18 @@ type HubitatConfiguration struct {
 
 // HubitatEvent represents a Hubitat event.
 type HubitatEvent struct {
-	Alias string     `json:"id"`
-	State LightState `json:"state"`
+	Alias string      `json:"id"`
+	State interface{} `json:"state"`
+
+	// Button and Action are set for the actions on the buttons of remotes.
+	Button string `json:"button,omitempty"`
+	Action string `json:"action,omitempty"`
+
+	// Priority is set to HubitatPriorityHigh for the events that raise
+	// alarms.
+	Priority string `json:"priority,omitempty"`
 }
+
+// HubitatPriorityHigh is the priority of the Hubitat events that report
+// alarms.
+const HubitatPriorityHigh = "high"
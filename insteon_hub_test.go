@@ -0,0 +1,264 @@
+package insteon
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeInsteonHub serves the Insteon Hub buffer protocol.
+type fakeInsteonHub struct {
+	lock   sync.Mutex
+	buffer [insteonHubBufferSize / 2]byte
+	index  int
+	frames [][]byte
+}
+
+func (h *fakeInsteonHub) push(data []byte) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for _, b := range data {
+		h.buffer[h.index] = b
+		h.index = (h.index + 1) % len(h.buffer)
+	}
+}
+
+func (h *fakeInsteonHub) sent() [][]byte {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return append([][]byte{}, h.frames...)
+}
+
+func (h *fakeInsteonHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	switch r.URL.Path {
+	case "/buffstatus.xml":
+		fmt.Fprintf(w, "<response><BS>%s%02X</BS></response>", strings.ToUpper(hex.EncodeToString(h.buffer[:])), h.index*2)
+	case "/3":
+		frame, err := hex.DecodeString(strings.TrimSuffix(r.URL.RawQuery, "=I=3"))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.frames = append(h.frames, frame)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestInsteonHub(t *testing.T, initial []byte) (*fakeInsteonHub, *insteonHubReadWriteCloser) {
+	hub := &fakeInsteonHub{}
+	hub.push(initial)
+
+	server := httptest.NewServer(hub)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+
+	if err != nil {
+		t.Fatalf("parsing server URL: %s", err)
+	}
+
+	device, err := newInsteonHubReadWriteCloser(&url.URL{Scheme: "hub", Host: u.Host})
+
+	if err != nil {
+		t.Fatalf("opening Insteon Hub: %s", err)
+	}
+
+	t.Cleanup(func() { device.Close() })
+
+	return hub, device
+}
+
+func readInsteonHub(t *testing.T, device *insteonHubReadWriteCloser, size int) ([]byte, error) {
+	result := make(chan error, 1)
+	data := make([]byte, size)
+
+	go func() {
+		_, err := io.ReadFull(device, data)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		return data, err
+	case <-time.After(time.Second * 5):
+		t.Fatalf("timed out reading %d byte(s)", size)
+	}
+
+	return nil, nil
+}
+
+func TestInsteonHubWrite(t *testing.T) {
+	hub, device := newTestInsteonHub(t, nil)
+
+	frame := []byte{0x02, 0x62, 0x11, 0x22, 0x33, 0x0f, 0x11, 0xff}
+
+	if _, err := device.Write(frame); err != nil {
+		t.Fatalf("writing frame: %s", err)
+	}
+
+	frames := hub.sent()
+
+	if len(frames) != 1 || !bytes.Equal(frames[0], frame) {
+		t.Errorf("expected %x to be sent but got %x", frame, frames)
+	}
+}
+
+func TestInsteonHubRead(t *testing.T) {
+	bufferSize := insteonHubBufferSize / 2
+	response := []byte{0x02, 0x60, 0x11, 0x22, 0x33, 0x03, 0x15, 0x9b, 0x06}
+
+	testCases := []struct {
+		name    string
+		initial int
+		writes  [][]byte
+	}{
+		{
+			name:    "single-poll",
+			initial: 10,
+			writes:  [][]byte{response},
+		},
+		{
+			name:    "several-polls",
+			initial: 10,
+			writes:  [][]byte{response[:4], response[4:]},
+		},
+		{
+			name:    "wrap-around",
+			initial: bufferSize - 4,
+			writes:  [][]byte{response},
+		},
+		{
+			name:    "whole-buffer",
+			initial: 42,
+			writes:  [][]byte{bytes.Repeat([]byte{0x01}, bufferSize-1)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			hub, device := newTestInsteonHub(t, bytes.Repeat([]byte{0xaa}, testCase.initial))
+
+			for _, data := range testCase.writes {
+				hub.push(data)
+
+				result, err := readInsteonHub(t, device, len(data))
+
+				if err != nil {
+					t.Fatalf("reading: %s", err)
+				}
+
+				if !bytes.Equal(result, data) {
+					t.Errorf("expected %x but got %x", data, result)
+				}
+			}
+		})
+	}
+}
+
+func TestInsteonHubOverrun(t *testing.T) {
+	bufferSize := insteonHubBufferSize / 2
+
+	testCases := []struct {
+		name string
+		size int
+	}{
+		{
+			name: "whole-buffer",
+			size: bufferSize,
+		},
+		{
+			name: "more-than-buffer",
+			size: bufferSize + 12,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			hub, device := newTestInsteonHub(t, nil)
+
+			data := make([]byte, testCase.size)
+
+			for i := range data {
+				data[i] = byte(i + 1)
+			}
+
+			hub.push(data)
+
+			if _, err := readInsteonHub(t, device, testCase.size); err != ErrInsteonHubBufferOverrun {
+				t.Errorf("expected %s but got: %v", ErrInsteonHubBufferOverrun, err)
+			}
+		})
+	}
+}
+
+func TestInsteonHubBufferOverrun(t *testing.T) {
+	previous := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+
+	testCases := []struct {
+		name     string
+		buffer   []byte
+		from     int
+		to       int
+		expected bool
+	}{
+		{
+			name:     "no-change",
+			buffer:   []byte{0, 1, 2, 3, 4, 5, 6, 7},
+			from:     3,
+			to:       3,
+			expected: false,
+		},
+		{
+			name:     "new-data",
+			buffer:   []byte{0, 1, 2, 9, 9, 5, 6, 7},
+			from:     3,
+			to:       5,
+			expected: false,
+		},
+		{
+			name:     "new-data-wrapping",
+			buffer:   []byte{9, 1, 2, 3, 4, 5, 9, 9},
+			from:     6,
+			to:       1,
+			expected: false,
+		},
+		{
+			name:     "whole-buffer",
+			buffer:   []byte{9, 9, 9, 9, 9, 9, 9, 9},
+			from:     3,
+			to:       3,
+			expected: true,
+		},
+		{
+			name:     "overwritten",
+			buffer:   []byte{9, 9, 9, 9, 9, 9, 9, 9},
+			from:     3,
+			to:       5,
+			expected: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if value := insteonHubBufferOverrun(previous, testCase.buffer, testCase.from, testCase.to); value != testCase.expected {
+				t.Errorf("expected %t but got %t", testCase.expected, value)
+			}
+		})
+	}
+}
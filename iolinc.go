@@ -0,0 +1,407 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	commandBytesGetSensor         = [2]byte{0x19, 0x01}
+	commandBytesGetOperatingFlags = [2]byte{0x1f, 0x00}
+)
+
+const (
+	cmd1SetOperatingFlags          byte = 0x20
+	ioLincDataSetMomentaryDuration byte = 0x06
+)
+
+// The operating flags of I/O Lincs, as read.
+const (
+	ioLincOperatingFlagMomentary    byte = 0x08
+	ioLincOperatingFlagTriggerOnOff byte = 0x10
+	ioLincOperatingFlagSense        byte = 0x20
+)
+
+// The second command bytes of the set operating flags command of I/O Lincs.
+const (
+	ioLincMomentaryOn     byte = 0x06
+	ioLincMomentaryOff    byte = 0x07
+	ioLincTriggerOnOffOn  byte = 0x12
+	ioLincTriggerOnOffOff byte = 0x13
+	ioLincSenseOn         byte = 0x14
+	ioLincSenseOff        byte = 0x15
+)
+
+// IOLinc is an I/O Linc: a device with a sensor input, reported on group 1,
+// and a relay output.
+//
+// I/O Lincs also implement GarageDoor.
+type IOLinc interface {
+	Relay
+	GetSensor(ctx context.Context) (bool, error)
+	GetConfiguration(ctx context.Context) (*IOLincConfiguration, error)
+	SetConfiguration(ctx context.Context, configuration IOLincConfiguration) error
+}
+
+// RelayMode is the mode of the relay of an I/O Linc.
+type RelayMode int
+
+const (
+	// RelayLatching makes the relay stay in the state it is set to.
+	RelayLatching RelayMode = iota
+	// RelayMomentaryA makes the relay close for the momentary duration when
+	// it is set on, and ignore off commands.
+	RelayMomentaryA
+	// RelayMomentaryB makes the relay close for the momentary duration on
+	// both on and off commands.
+	RelayMomentaryB
+	// RelayMomentaryC makes the relay close for the momentary duration when
+	// the command matches the sensor state.
+	RelayMomentaryC
+)
+
+var relayModes = []string{"latching", "momentary-a", "momentary-b", "momentary-c"}
+
+func (m RelayMode) String() string {
+	if int(m) < len(relayModes) {
+		return relayModes[m]
+	}
+
+	return fmt.Sprintf("unknown relay mode %d", int(m))
+}
+
+// UnmarshalText -
+func (m *RelayMode) UnmarshalText(b []byte) error {
+	for i, mode := range relayModes {
+		if mode == string(b) {
+			*m = RelayMode(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported relay mode: %s", string(b))
+}
+
+// MarshalText -
+func (m RelayMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// IOLincConfiguration represents the configuration of an I/O Linc.
+type IOLincConfiguration struct {
+	RelayMode         RelayMode     `json:"relay_mode"`
+	MomentaryDuration time.Duration `json:"momentary_duration"`
+}
+
+// IOLincState represents the state of an I/O Linc.
+type IOLincState struct {
+	Relay  bool `json:"relay"`
+	Sensor bool `json:"sensor"`
+}
+
+type ioLinc struct {
+	relay
+
+	lock          sync.Mutex
+	lastTrigger   time.Time
+	triggeredFrom DoorState
+}
+
+// GetSensor returns whether the sensor input is on.
+func (d *ioLinc) GetSensor(ctx context.Context) (bool, error) {
+	value, err := d.Cached(ctx, "sensor", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		response, err := plm.SendCommand(ctx, d.id, NewCommand(commandBytesGetSensor))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return response.Ack.CommandBytes[1] != 0, nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return value.(bool), nil
+}
+
+// GetConfiguration returns the configuration of the device.
+//
+// The relay mode is read from the operating flags and the momentary
+// duration, in tenths of seconds, from D4 of the extended data set.
+func (d *ioLinc) GetConfiguration(ctx context.Context) (*IOLincConfiguration, error) {
+	value, err := d.Cached(ctx, "configuration", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		response, err := plm.SendCommand(ctx, d.id, NewCommand(commandBytesGetOperatingFlags))
+
+		if err != nil {
+			return nil, err
+		}
+
+		configuration := &IOLincConfiguration{}
+
+		switch flags := response.Ack.CommandBytes[1]; {
+		case flags&ioLincOperatingFlagMomentary == 0:
+			configuration.RelayMode = RelayLatching
+		case flags&ioLincOperatingFlagTriggerOnOff != 0:
+			configuration.RelayMode = RelayMomentaryB
+		case flags&ioLincOperatingFlagSense != 0:
+			configuration.RelayMode = RelayMomentaryC
+		default:
+			configuration.RelayMode = RelayMomentaryA
+		}
+
+		command := NewExtendedCommand(commandBytesExtendedGetSet, [14]byte{})
+		command.ExtendedResponse = true
+
+		if response, err = plm.SendCommand(ctx, d.id, command); err != nil {
+			return nil, err
+		}
+
+		if response.Extended == nil {
+			return nil, fmt.Errorf("no data set received from %s", d.id)
+		}
+
+		configuration.MomentaryDuration = time.Duration(response.Extended.UserData[3]) * time.Second / 10
+
+		return configuration, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*IOLincConfiguration), nil
+}
+
+// SetConfiguration sets the configuration of the device.
+func (d *ioLinc) SetConfiguration(ctx context.Context, configuration IOLincConfiguration) error {
+	var flags []byte
+
+	switch configuration.RelayMode {
+	case RelayLatching:
+		flags = []byte{ioLincMomentaryOff}
+	case RelayMomentaryA:
+		flags = []byte{ioLincMomentaryOn, ioLincTriggerOnOffOff, ioLincSenseOff}
+	case RelayMomentaryB:
+		flags = []byte{ioLincMomentaryOn, ioLincTriggerOnOffOn, ioLincSenseOff}
+	case RelayMomentaryC:
+		flags = []byte{ioLincMomentaryOn, ioLincTriggerOnOffOff, ioLincSenseOn}
+	default:
+		return fmt.Errorf("unsupported relay mode: %s", configuration.RelayMode)
+	}
+
+	for _, flag := range flags {
+		if _, err := d.SendCommand(ctx, NewCommand([2]byte{cmd1SetOperatingFlags, flag})); err != nil {
+			return err
+		}
+	}
+
+	if configuration.RelayMode == RelayLatching {
+		return nil
+	}
+
+	// The duration is set in tenths of seconds, from 0.1 to 25.5 seconds.
+	duration := configuration.MomentaryDuration / (time.Second / 10)
+
+	if duration < 1 {
+		duration = 1
+	} else if duration > 0xff {
+		duration = 0xff
+	}
+
+	_, err := d.SendCommand(ctx, NewExtendedCommand(commandBytesExtendedGetSet, [14]byte{0x00, ioLincDataSetMomentaryDuration, byte(duration)}))
+
+	return err
+}
+
+// ioLincDriver is the driver of I/O Lincs.
+type ioLincDriver struct {
+	BaseDeviceDriver
+}
+
+func (ioLincDriver) Name() string {
+	return "iolinc"
+}
+
+func (ioLincDriver) NewDevice(base *BaseDevice) Device {
+	return &ioLinc{relay: relay{base}}
+}
+
+// DecodeEvent reports the broadcasts of the sensor input as sensor events.
+func (d ioLincDriver) DecodeEvent(msg *Message, event *DeviceEvent) bool {
+	if !d.BaseDeviceDriver.DecodeEvent(msg, event) {
+		return false
+	}
+
+	if event.Group == 1 {
+		event.Type = "sensor"
+		event.Attributes = map[string]interface{}{"sensor": bool(event.OnOff)}
+	}
+
+	return true
+}
+
+func (ioLincDriver) NewState() interface{} {
+	return &IOLincState{}
+}
+
+func (ioLincDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	ioLinc, ok := device.(IOLinc)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	relay, err := ioLinc.IsOn(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sensor, err := ioLinc.GetSensor(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &IOLincState{Relay: relay, Sensor: sensor}, nil
+}
+
+// SetState sets the relay of an I/O Linc, as the sensor is an input.
+func (ioLincDriver) SetState(ctx context.Context, device Device, state interface{}) error {
+	ioLinc, ok := device.(IOLinc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	ioLincState, ok := state.(*IOLincState)
+
+	if !ok {
+		return fmt.Errorf("unexpected state type: %T", state)
+	}
+
+	if ioLincState.Relay {
+		return ioLinc.On(ctx)
+	}
+
+	return ioLinc.Off(ctx)
+}
+
+func (ioLincDriver) NewInfo() interface{} {
+	return &IOLincConfiguration{}
+}
+
+func (ioLincDriver) GetInfo(ctx context.Context, device Device) (interface{}, error) {
+	ioLinc, ok := device.(IOLinc)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return ioLinc.GetConfiguration(ctx)
+}
+
+func (ioLincDriver) SetInfo(ctx context.Context, device Device, info interface{}) error {
+	ioLinc, ok := device.(IOLinc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	configuration, ok := info.(*IOLincConfiguration)
+
+	if !ok {
+		return fmt.Errorf("unexpected information type: %T", info)
+	}
+
+	return ioLinc.SetConfiguration(ctx, *configuration)
+}
+
+func (ioLincDriver) Commands() []DeviceCommand {
+	return append([]DeviceCommand{
+		{
+			Name:        "sensor",
+			Description: "Show whether the sensor input is on",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				ioLinc, ok := device.(IOLinc)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				return ioLinc.GetSensor(ctx)
+			},
+		},
+		{
+			Name:        "relay-mode",
+			Usage:       "<latching|momentary-a|momentary-b|momentary-c> [<duration>]",
+			Description: "Set the mode of the relay and its momentary duration",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				ioLinc, ok := device.(IOLinc)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				if len(args) < 1 || len(args) > 2 {
+					return nil, fmt.Errorf("expected a relay mode and an optional duration")
+				}
+
+				configuration := IOLincConfiguration{MomentaryDuration: time.Second * 2}
+
+				if err := configuration.RelayMode.UnmarshalText([]byte(args[0])); err != nil {
+					return nil, err
+				}
+
+				if len(args) == 2 {
+					duration, err := time.ParseDuration(args[1])
+
+					if err != nil {
+						return nil, err
+					}
+
+					configuration.MomentaryDuration = duration
+				}
+
+				return nil, ioLinc.SetConfiguration(ctx, configuration)
+			},
+		},
+		{
+			Name:        "door",
+			Usage:       "[open|close]",
+			Description: "Show the state of the garage door, or open or close it",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				door, ok := device.(GarageDoor)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				switch {
+				case len(args) == 0:
+					state, err := door.GetDoorState(ctx)
+
+					if err != nil {
+						return nil, err
+					}
+
+					return GarageDoorState{State: state}, nil
+				case len(args) == 1 && args[0] == "open":
+					return nil, door.OpenDoor(ctx)
+				case len(args) == 1 && args[0] == "close":
+					return nil, door.CloseDoor(ctx)
+				}
+
+				return nil, fmt.Errorf("expected open, close or no argument")
+			},
+		},
+	}, relayCommands...)
+}
+
+func init() {
+	RegisterMainCategoryDeviceDriver(ioLincDriver{}, sensorsAndActuators)
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"github.com/intelux/insteon"
 	"github.com/spf13/cobra"
 )
 
@@ -16,12 +15,12 @@ var beepCmd = &cobra.Command{
 			return err
 		}
 
-		if err := insteon.DefaultPowerLineModem.Beep(rootCtx, device.ID); err != nil {
+		if err := rootPLM.Beep(rootCtx, device.ID); err != nil {
 			return err
 		}
 
 		for _, id := range device.MirrorDeviceIDs {
-			insteon.DefaultPowerLineModem.Beep(rootCtx, id)
+			rootPLM.Beep(rootCtx, id)
 		}
 
 		return nil
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/intelux/insteon"
+	"github.com/spf13/cobra"
+)
+
+var (
+	detectCmdTimeout = time.Second
+)
+
+var detectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Detect the PowerLine Modems attached to the serial ports",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		detected := insteon.DetectPowerLineModems(rootCtx, detectCmdTimeout)
+
+		if len(detected) == 0 {
+			return fmt.Errorf("no candidate serial port found")
+		}
+
+		w := &tabwriter.Writer{}
+		w.Init(os.Stdout, 0, 8, 0, '\t', 0)
+		fmt.Fprintf(w, "Device\tPLM\tID\tCategory\tFirmware version\n")
+
+		for _, d := range detected {
+			switch {
+			case d.IMInfo != nil:
+				isPLM := "no"
+
+				if d.IsPowerLineModem() {
+					isPLM = "yes"
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", d.Device, isPLM, d.IMInfo.ID, d.IMInfo.Category, d.IMInfo.FirmwareVersion)
+			default:
+				fmt.Fprintf(w, "%s\tno\t-\t%s\t-\n", d.Device, d.Error)
+			}
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	detectCmd.Flags().DurationVarP(&detectCmdTimeout, "timeout", "t", detectCmdTimeout, "The time given to each serial port to answer.")
+
+	rootCmd.AddCommand(detectCmd)
+}
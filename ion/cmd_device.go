@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var deviceCmd = &cobra.Command{
+	Use:   "device <device> [command [args...]]",
+	Short: "Run a command of the driver of a device",
+	Long:  "Run a command of the driver of a device. Without a command, the commands supported by the device are listed.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		device, err := rootConfig.LookupDevice(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			handle, err := rootDevices.Device(rootCtx, device.ID)
+
+			if err != nil {
+				return err
+			}
+
+			w := &tabwriter.Writer{}
+			w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+			fmt.Fprintf(w, "Driver\t%s\n\n", handle.Driver().Name())
+			fmt.Fprintf(w, "Command\tDescription\n")
+
+			for _, command := range handle.Driver().Commands() {
+				fmt.Fprintf(w, "%s\t%s\n", strings.TrimSpace(command.Name+" "+command.Usage), command.Description)
+			}
+
+			return w.Flush()
+		}
+
+		result, err := rootDevices.RunCommand(rootCtx, device.ID, args[1], args[2:])
+
+		if err != nil {
+			return err
+		}
+
+		if result == nil {
+			return nil
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deviceCmd)
+}
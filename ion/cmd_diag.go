@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/intelux/insteon"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diagCmdCount    = 5
+	diagCmdInterval = time.Millisecond * 500
+)
+
+var diagCmd = &cobra.Command{
+	Use:   "diag",
+	Short: "Report the link quality of all the configured devices",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := &tabwriter.Writer{}
+		w.Init(os.Stdout, 0, 8, 0, '\t', 0)
+		fmt.Fprintf(w, "Device\tID\tLoss\tp50\tp90\tMax hops used\tNAKs\n")
+
+		for _, device := range rootConfig.Devices {
+			report := insteon.Diagnose(rootCtx, rootPLM, device.ID, diagCmdCount, diagCmdInterval, nil)
+
+			if rootCtx.Err() != nil {
+				break
+			}
+
+			maxHopsUsed := -1
+
+			for hops := range report.HopsUsed {
+				if hops > maxHopsUsed {
+					maxHopsUsed = hops
+				}
+			}
+
+			naks := 0
+
+			for _, count := range report.Naks {
+				naks += count
+			}
+
+			hops := "-"
+
+			if maxHopsUsed >= 0 {
+				hops = fmt.Sprintf("%d", maxHopsUsed)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%.0f%%\t%s\t%s\t%s\t%d\n", device.Alias, device.ID, report.Loss*100, report.Latency.P50, report.Latency.P90, hops, naks)
+		}
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	diagCmd.Flags().IntVarP(&diagCmdCount, "count", "c", diagCmdCount, "The number of pings to send to each device.")
+	diagCmd.Flags().DurationVarP(&diagCmdInterval, "interval", "i", diagCmdInterval, "The interval between pings.")
+
+	rootCmd.AddCommand(diagCmd)
+}
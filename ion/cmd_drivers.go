@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/intelux/insteon"
+	"github.com/spf13/cobra"
+)
+
+var driversCmd = &cobra.Command{
+	Use:   "drivers",
+	Short: "List the device drivers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := &tabwriter.Writer{}
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Driver\tState\tInfo\tCommands\n")
+
+		for _, driver := range insteon.DeviceDrivers() {
+			description := insteon.DescribeDeviceDriver(driver)
+			commands := make([]string, len(description.Commands))
+
+			for i, command := range description.Commands {
+				commands[i] = command.Name
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", description.Name, describeFields(description.State), describeFields(description.Info), strings.Join(commands, ", "))
+		}
+
+		return w.Flush()
+	},
+}
+
+// describeFields returns the sorted names of the fields of a schema.
+func describeFields(schema map[string]string) string {
+	if len(schema) == 0 {
+		return "-"
+	}
+
+	fields := make([]string, 0, len(schema))
+
+	for name := range schema {
+		fields = append(fields, name)
+	}
+
+	sort.Strings(fields)
+
+	return strings.Join(fields, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(driversCmd)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/intelux/insteon"
+	"github.com/spf13/cobra"
+)
+
+var fanCmd = &cobra.Command{
+	Use:       "fan <device> [off|low|medium|high]",
+	Short:     "Get or set the speed of a fan",
+	Args:      cobra.RangeArgs(1, 2),
+	ValidArgs: []string{"off", "low", "medium", "high"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		device, err := rootConfig.LookupDevice(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		handle, err := rootDevices.Device(rootCtx, device.ID)
+
+		if err != nil {
+			return err
+		}
+
+		fan, ok := handle.(insteon.Fan)
+
+		if !ok {
+			return fmt.Errorf("device %s (%s) is not a fan", device.Name, device.ID)
+		}
+
+		if len(args) == 1 {
+			speed, err := fan.GetSpeed(rootCtx)
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(speed)
+
+			return nil
+		}
+
+		var speed insteon.FanSpeed
+
+		if err := speed.UnmarshalText([]byte(args[1])); err != nil {
+			return err
+		}
+
+		return fan.SetSpeed(rootCtx, speed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fanCmd)
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 	"text/tabwriter"
 
-	"github.com/intelux/insteon"
 	"github.com/spf13/cobra"
 )
 
@@ -15,7 +14,7 @@ var getAllLinkDBCmd = &cobra.Command{
 	Short: "Get the AllLink database of the PowerLine Modem",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		records, err := insteon.DefaultPowerLineModem.GetAllLinkDB(rootCtx)
+		records, err := rootPLM.GetAllLinkDB(rootCtx)
 
 		if err != nil {
 			return err
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var getCategoryCmd = &cobra.Command{
+	Use:   "get-category <device>",
+	Short: "Get the category of a device",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		device, err := rootConfig.LookupDevice(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		category, err := rootPLM.GetDeviceCategory(rootCtx, device.ID)
+
+		if err != nil {
+			return err
+		}
+
+		w := &tabwriter.Writer{}
+		w.Init(os.Stdout, 0, 8, 0, '\t', 0)
+		fmt.Fprintf(w, "Category\tMain\tSub\n")
+		fmt.Fprintf(w, "%s\t%02x\t%02x\n", category, category.MainCategory, category.SubCategory)
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getCategoryCmd)
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 	"text/tabwriter"
 
-	"github.com/intelux/insteon"
 	"github.com/spf13/cobra"
 )
 
@@ -21,7 +20,7 @@ var getDeviceInfoCmd = &cobra.Command{
 			return err
 		}
 
-		deviceInfo, err := insteon.DefaultPowerLineModem.GetDeviceInfo(rootCtx, device.ID)
+		deviceInfo, err := rootPLM.GetDeviceInfo(rootCtx, device.ID)
 
 		if err != nil {
 			return err
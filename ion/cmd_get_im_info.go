@@ -5,7 +5,6 @@ import (
 	"os"
 	"text/tabwriter"
 
-	"github.com/intelux/insteon"
 	"github.com/spf13/cobra"
 )
 
@@ -14,7 +13,7 @@ var getIMInfoCmd = &cobra.Command{
 	Short: "Get information about the PowerLine Modem itself",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		imInfo, err := insteon.DefaultPowerLineModem.GetIMInfo(rootCtx)
+		imInfo, err := rootPLM.GetIMInfo(rootCtx)
 
 		if err != nil {
 			return err
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 
-	"github.com/intelux/insteon"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +17,7 @@ var getLevelCmd = &cobra.Command{
 			return err
 		}
 
-		state, err := insteon.DefaultPowerLineModem.GetDeviceState(rootCtx, device.ID)
+		state, err := rootPLM.GetDeviceState(rootCtx, device.ID)
 
 		if err != nil {
 			return err
@@ -3,7 +3,6 @@ package main
 import (
 	"fmt"
 
-	"github.com/intelux/insteon"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +17,7 @@ var getRampRateCmd = &cobra.Command{
 			return err
 		}
 
-		deviceInfo, err := insteon.DefaultPowerLineModem.GetDeviceInfo(rootCtx, device.ID)
+		deviceInfo, err := rootPLM.GetDeviceInfo(rootCtx, device.ID)
 
 		if err != nil {
 			return err
@@ -20,7 +20,7 @@ var monitorCmd = &cobra.Command{
 			}
 		}()
 
-		insteon.DefaultPowerLineModem.Monitor(rootCtx, events)
+		rootDevices.Monitor(rootCtx, events)
 		close(events)
 
 		return nil
@@ -41,12 +41,16 @@ var offCmd = &cobra.Command{
 			Change: change,
 		}
 
-		if err := insteon.DefaultPowerLineModem.SetDeviceState(rootCtx, device.ID, state); err != nil {
+		if device.Outlet != 0 {
+			if err := setOutlet(device, false); err != nil {
+				return err
+			}
+		} else if err := rootPLM.SetDeviceState(rootCtx, device.ID, state); err != nil {
 			return err
 		}
 
 		for _, id := range device.MirrorDeviceIDs {
-			insteon.DefaultPowerLineModem.SetDeviceState(rootCtx, id, state)
+			rootPLM.SetDeviceState(rootCtx, id, state)
 		}
 
 		return nil
@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/intelux/insteon"
 	"github.com/spf13/cobra"
@@ -42,18 +43,39 @@ var onCmd = &cobra.Command{
 			Change: change,
 		}
 
-		if err := insteon.DefaultPowerLineModem.SetDeviceState(rootCtx, device.ID, state); err != nil {
+		if device.Outlet != 0 {
+			if err := setOutlet(device, true); err != nil {
+				return err
+			}
+		} else if err := rootPLM.SetDeviceState(rootCtx, device.ID, state); err != nil {
 			return err
 		}
 
 		for _, id := range device.MirrorDeviceIDs {
-			insteon.DefaultPowerLineModem.SetDeviceState(rootCtx, id, state)
+			rootPLM.SetDeviceState(rootCtx, id, state)
 		}
 
 		return nil
 	},
 }
 
+// setOutlet turns on or off an outlet of an OutletLinc.
+func setOutlet(device *insteon.ConfigurationDevice, on bool) error {
+	handle, err := rootDevices.Device(rootCtx, device.ID)
+
+	if err != nil {
+		return err
+	}
+
+	outletLinc, ok := handle.(insteon.OutletLinc)
+
+	if !ok {
+		return fmt.Errorf("device %s is not an outlet linc", device.Alias)
+	}
+
+	return outletLinc.SetOutlet(rootCtx, device.Outlet, on)
+}
+
 func init() {
 	onCmd.Flags().BoolVarP(&onCmdInstant, "instant", "i", false, "Change the light state instantly and at full value (level is ignored). Incompatible with --step.")
 	onCmd.Flags().BoolVarP(&onCmdStep, "step", "s", false, "Change the light state by step (level is ignored). Incompatible with --instant.")
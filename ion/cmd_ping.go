@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intelux/insteon"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pingCmdCount    = 10
+	pingCmdInterval = time.Second
+)
+
+var pingCmd = &cobra.Command{
+	Use:   "ping <device>",
+	Short: "Ping a device repeatedly and report its link quality",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		device, err := rootConfig.LookupDevice(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		results := make(chan insteon.PingResult)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			for result := range results {
+				if result.Nak != nil {
+					fmt.Printf("NAK from %s: %s, time=%s hops=%d/%d\n", device.ID, *result.Nak, result.Latency, result.HopsUsed, result.Hops)
+				} else {
+					fmt.Printf("ACK from %s: time=%s hops=%d/%d\n", device.ID, result.Latency, result.HopsUsed, result.Hops)
+				}
+			}
+		}()
+
+		report := insteon.Diagnose(rootCtx, rootPLM, device.ID, pingCmdCount, pingCmdInterval, results)
+		close(results)
+		<-done
+
+		fmt.Printf("\n--- %s (%s) ping statistics ---\n", device.Alias, device.ID)
+		fmt.Printf("%d sent, %d received, %.1f%% loss\n", report.Sent, report.Received, report.Loss*100)
+
+		if report.Received > 0 {
+			fmt.Printf("latency min/p50/p90/p99/max = %s/%s/%s/%s/%s\n", report.Latency.Min, report.Latency.P50, report.Latency.P90, report.Latency.P99, report.Latency.Max)
+		}
+
+		for hops, count := range report.HopsUsed {
+			fmt.Printf("%d acknowledgment(s) using %d hop(s)\n", count, hops)
+		}
+
+		for reason, count := range report.Naks {
+			fmt.Printf("%d NAK(s): %s\n", count, reason)
+		}
+
+		for _, err := range report.Errors {
+			fmt.Printf("error: %s\n", err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	pingCmd.Flags().IntVarP(&pingCmdCount, "count", "c", pingCmdCount, "The number of pings to send.")
+	pingCmd.Flags().DurationVarP(&pingCmdInterval, "interval", "i", pingCmdInterval, "The interval between pings.")
+
+	rootCmd.AddCommand(pingCmd)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/intelux/insteon"
+	"github.com/spf13/cobra"
+)
+
+var (
+	plmServerCmdListen      = []string{"tcp://:9761"}
+	plmServerCmdTLSCert     string
+	plmServerCmdTLSKey      string
+	plmServerCmdTLSClientCA string
+	plmServerCmdPSKFile     string
+)
+
+var plmServerCmd = &cobra.Command{
+	Use:   "plm-server",
+	Short: "Share the PowerLine Modem with several clients",
+	Long: `Share the PowerLine Modem with several clients
+
+Clients connect with a tcp://, tls:// or unix:// PowerLine Modem device URL.
+
+With --psk-file, clients on any of the addresses must add a psk=<file> query
+parameter to that URL.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		localPLM, err := openLocalPowerLineModem()
+
+		if err != nil {
+			return err
+		}
+
+		plm, ok := localPLM.(*insteon.SerialPowerLineModem)
+
+		if !ok {
+			return errors.New("the PowerLine Modem device can't be shared")
+		}
+
+		server := insteon.NewPowerLineModemServer(plm)
+
+		if plmServerCmdPSKFile != "" {
+			if server.PreSharedKey, err = insteon.LoadPreSharedKey(plmServerCmdPSKFile); err != nil {
+				return err
+			}
+		}
+		errs := make(chan error, len(plmServerCmdListen))
+
+		for _, address := range plmServerCmdListen {
+			listener, err := listen(address)
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "Sharing PowerLine Modem on %s.\n", address)
+
+			go func() {
+				errs <- server.Serve(rootCtx, listener)
+			}()
+		}
+
+		for range plmServerCmdListen {
+			if err := <-errs; err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "Stop sharing PowerLine Modem.\n")
+
+		return nil
+	},
+}
+
+func listen(address string) (net.Listener, error) {
+	u, err := url.Parse(address)
+
+	if err != nil {
+		return nil, fmt.Errorf("parsing listen address: %s", err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		return net.Listen("tcp", u.Host)
+	case "unix":
+		return net.Listen("unix", u.Path)
+	case "tls":
+		if plmServerCmdTLSCert == "" {
+			return nil, errors.New("tls:// addresses require a certificate (--tls-cert and --tls-key)")
+		}
+
+		config, err := insteon.NewServerTLSConfig(plmServerCmdTLSCert, plmServerCmdTLSKey, plmServerCmdTLSClientCA)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return tls.Listen("tcp", u.Host, config)
+	default:
+		return nil, fmt.Errorf("unsupported listen address: %s", address)
+	}
+}
+
+func init() {
+	plmServerCmd.Flags().StringSliceVarP(&plmServerCmdListen, "listen", "l", plmServerCmdListen, "The tcp://, tls:// or unix:// addresses to listen on.")
+	plmServerCmd.Flags().StringVar(&plmServerCmdTLSCert, "tls-cert", "", "The server certificate file, for tls:// addresses.")
+	plmServerCmd.Flags().StringVar(&plmServerCmdTLSKey, "tls-key", "", "The server private key file, for tls:// addresses.")
+	plmServerCmd.Flags().StringVar(&plmServerCmdTLSClientCA, "tls-client-ca", "", "The CA certificates file to verify client certificates with. If set, clients must present a certificate.")
+	plmServerCmd.Flags().StringVar(&plmServerCmdPSKFile, "psk-file", "", "The file of a pre-shared key that clients must prove they know.")
+
+	rootCmd.AddCommand(plmServerCmd)
+}
@@ -21,7 +21,13 @@ var serverCmd = &cobra.Command{
 	Short: "Start a control server",
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		webService := insteon.NewWebService(nil, rootConfig)
+		plm, err := openLocalPowerLineModem()
+
+		if err != nil {
+			return err
+		}
+
+		webService := insteon.NewWebService(plm, rootConfig)
 
 		if err := webService.Synchronize(rootCtx, serverCmdOptimize); err != nil {
 			return err
@@ -46,6 +52,15 @@ var serverCmd = &cobra.Command{
 
 		fmt.Fprintf(os.Stderr, "Started HTTP web-service on %s.\n", serverCmdEndpoint)
 
+		// Let the other commands find us.
+		release, err := insteon.RegisterServer(insteon.ServerURLFromEndpoint(serverCmdEndpoint))
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to register the web-service: %s.\n", err)
+		} else {
+			defer release()
+		}
+
 		go webService.Run(rootCtx)
 
 		if err := server.ListenAndServe(); err != http.ErrServerClosed {
@@ -28,12 +28,12 @@ var setLEDBrightnessCmd = &cobra.Command{
 			LEDBrightness: &level,
 		}
 
-		if err := insteon.DefaultPowerLineModem.SetDeviceInfo(rootCtx, device.ID, deviceInfo); err != nil {
+		if err := rootPLM.SetDeviceInfo(rootCtx, device.ID, deviceInfo); err != nil {
 			return err
 		}
 
 		for _, id := range device.MirrorDeviceIDs {
-			insteon.DefaultPowerLineModem.SetDeviceInfo(rootCtx, id, deviceInfo)
+			rootPLM.SetDeviceInfo(rootCtx, id, deviceInfo)
 		}
 
 		return nil
@@ -31,12 +31,12 @@ var setLevelCmd = &cobra.Command{
 			Change: insteon.ChangeNormal,
 		}
 
-		if err := insteon.DefaultPowerLineModem.SetDeviceState(rootCtx, device.ID, state); err != nil {
+		if err := rootPLM.SetDeviceState(rootCtx, device.ID, state); err != nil {
 			return err
 		}
 
 		for _, id := range device.MirrorDeviceIDs {
-			insteon.DefaultPowerLineModem.SetDeviceState(rootCtx, id, state)
+			rootPLM.SetDeviceState(rootCtx, id, state)
 		}
 
 		return nil
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/intelux/insteon"
+	"github.com/spf13/cobra"
+)
+
+var thermostatCmd = &cobra.Command{
+	Use:   "thermostat <device> [mode|fan|cool|heat|sync-clock [value]]",
+	Short: "Get the status of a thermostat or change its settings",
+	Long:  "Get the status of a thermostat or change its settings: its mode (off, auto, heat, cool or program), its fan mode (auto or on), its cool and heat setpoints (in degrees Celsius) or its clock.",
+	Args:  cobra.RangeArgs(1, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		device, err := rootConfig.LookupDevice(args[0])
+
+		if err != nil {
+			return err
+		}
+
+		if len(args) > 1 {
+			_, err := rootDevices.RunCommand(rootCtx, device.ID, args[1], args[2:])
+
+			return err
+		}
+
+		handle, err := rootDevices.Device(rootCtx, device.ID)
+
+		if err != nil {
+			return err
+		}
+
+		thermostat, ok := handle.(insteon.Thermostat)
+
+		if !ok {
+			return fmt.Errorf("device %s (%s) is not a thermostat", device.Name, device.ID)
+		}
+
+		status, err := thermostat.GetStatus(rootCtx)
+
+		if err != nil {
+			return err
+		}
+
+		w := &tabwriter.Writer{}
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprintf(w, "Temperature\t%.1f°C\n", status.Temperature)
+		fmt.Fprintf(w, "Humidity\t%.0f%%\n", status.Humidity*100)
+		fmt.Fprintf(w, "Mode\t%s\n", status.Mode)
+		fmt.Fprintf(w, "Fan mode\t%s\n", status.FanMode)
+		fmt.Fprintf(w, "Cool setpoint\t%.1f°C\n", status.CoolSetpoint)
+		fmt.Fprintf(w, "Heat setpoint\t%.1f°C\n", status.HeatSetpoint)
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(thermostatCmd)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
 
@@ -12,22 +14,51 @@ import (
 var (
 	rootCtx, rootCtxCancel = withInterrupt(context.Background())
 	rootConfig             *insteon.Configuration
+	rootPLM                insteon.PowerLineModem
+	rootDevices            *insteon.Devices
+	rootPLMDevice          string
 )
 
 var rootCmd = &cobra.Command{
 	Use: "ion",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) (err error) {
-		rootConfig, err = insteon.LoadDefaultConfiguration()
+		if rootConfig, err = insteon.LoadDefaultConfiguration(); err != nil {
+			return
+		}
+
+		// Remember the number of hops of the devices from one run to the
+		// next.
+		if insteon.PowerLineModemHops, err = insteon.LoadDefaultHopsTracker(); err != nil {
+			fmt.Fprintf(os.Stderr, "Loading hops: %s.\n", err)
+			insteon.PowerLineModemHops = nil
+		}
+
+		if rootPLM, err = openPowerLineModem(); err != nil {
+			return
+		}
+
+		rootDevices = insteon.NewDevices(rootPLM, rootConfig)
 
 		return
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		rootCtxCancel()
+		closeRootPowerLineModem()
+
+		if err := insteon.PowerLineModemHops.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Saving hops: %s.\n", err)
+		}
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&rootPLMDevice, "plm", "", "The PowerLine Modem device or server URL to use. Defaults to a running server, if any, or to the local device.")
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		// PersistentPostRun is skipped on errors.
+		closeRootPowerLineModem()
 		os.Exit(1)
 	}
 }
@@ -52,3 +83,47 @@ func withInterrupt(ctx context.Context) (context.Context, func()) {
 
 	return ctx, cancel
 }
+
+// openPowerLineModem returns the PowerLine Modem specified on the
+// command-line, or the one of a running server, or the local one.
+func openPowerLineModem() (insteon.PowerLineModem, error) {
+	if rootPLMDevice != "" {
+		return insteon.NewPowerLineModem(rootPLMDevice)
+	}
+
+	if serverURL, ok := insteon.FindServer(rootConfig); ok {
+		return insteon.NewHTTPPowerLineModem(serverURL)
+	}
+
+	return insteon.DefaultPowerLineModem, nil
+}
+
+// openLocalPowerLineModem returns the PowerLine Modem specified on the
+// command-line, or the local one, but never the one of a running server.
+func openLocalPowerLineModem() (insteon.PowerLineModem, error) {
+	// The device specified on the command-line is already open, and locked.
+	if rootPLMDevice != "" {
+		return rootPLM, nil
+	}
+
+	plm, err := insteon.NewPowerLineModem(insteon.PowerLineModemDevice)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing used the previous one yet: this one is closed instead.
+	rootPLM = plm
+
+	return plm, nil
+}
+
+// closeRootPowerLineModem closes the PowerLine Modem, which releases the lock
+// on its device.
+func closeRootPowerLineModem() {
+	if closer, ok := rootPLM.(io.Closer); ok {
+		closer.Close()
+	}
+
+	rootPLM = nil
+}
@@ -0,0 +1,560 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	commandBytesGetLEDMask     = [2]byte{0x19, 0x01}
+	commandBytesExtendedGetSet = [2]byte{0x2e, 0x00}
+)
+
+// The data set codes of the extended set command of keypads.
+const (
+	keypadDataSetOnMask         byte = 0x02
+	keypadDataSetOffMask        byte = 0x03
+	keypadDataSetRampRate       byte = 0x05
+	keypadDataSetOnLevel        byte = 0x06
+	keypadDataSetNonToggleMask  byte = 0x08
+	keypadDataSetLEDMask        byte = 0x09
+	keypadDataSetNonToggleOnOff byte = 0x0b
+)
+
+// Keypad is a KeypadLinc: a device that controls a load and has several
+// buttons.
+//
+// Buttons are identified by the group they send their broadcasts on. The
+// first button controls the load.
+//
+// Keypads that control a dimmable load also implement Dimmer.
+type Keypad interface {
+	Relay
+	Buttons() int
+	ButtonGroups() []Group
+	GetLEDMask(ctx context.Context) (byte, error)
+	SetLEDMask(ctx context.Context, mask byte) error
+	GetButtonInfo(ctx context.Context, button Group) (*KeypadButtonInfo, error)
+	SetButtonInfo(ctx context.Context, button Group, info KeypadButtonInfo) error
+}
+
+// ButtonToggleMode is the toggle mode of a keypad button.
+type ButtonToggleMode int
+
+const (
+	// ToggleModeToggle makes a button alternatively send on and off.
+	ToggleModeToggle ButtonToggleMode = iota
+	// ToggleModeOn makes a button always send on.
+	ToggleModeOn
+	// ToggleModeOff makes a button always send off.
+	ToggleModeOff
+)
+
+func (m ButtonToggleMode) String() string {
+	switch m {
+	case ToggleModeOn:
+		return "on"
+	case ToggleModeOff:
+		return "off"
+	default:
+		return "toggle"
+	}
+}
+
+// UnmarshalText -
+func (m *ButtonToggleMode) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "toggle":
+		*m = ToggleModeToggle
+	case "on":
+		*m = ToggleModeOn
+	case "off":
+		*m = ToggleModeOff
+	default:
+		return fmt.Errorf("unsupported toggle mode: %s", string(b))
+	}
+
+	return nil
+}
+
+// MarshalText -
+func (m ButtonToggleMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// KeypadButtonInfo represents the settings of a keypad button.
+//
+// The on and off masks are the buttons whose LEDs are turned off when the
+// button is turned on or off, respectively.
+type KeypadButtonInfo struct {
+	OnLevel    float64          `json:"on_level"`
+	RampRate   time.Duration    `json:"ramp_rate"`
+	OnMask     byte             `json:"on_mask"`
+	OffMask    byte             `json:"off_mask"`
+	ToggleMode ButtonToggleMode `json:"toggle_mode"`
+}
+
+// KeypadButtonState represents the state of a keypad button, as exposed by
+// the API.
+//
+// LED and Info are optional when setting the state: only the ones that are
+// specified are changed.
+type KeypadButtonState struct {
+	Group Group             `json:"group"`
+	Name  string            `json:"description,omitempty"`
+	Alias string            `json:"id,omitempty"`
+	LED   *bool             `json:"led,omitempty"`
+	Info  *KeypadButtonInfo `json:"info,omitempty"`
+}
+
+// keypadButtonData is the data set of a keypad button, as returned by the
+// extended get command.
+//
+// The layout is the one of the KeypadLinc developer notes: D3 is the on
+// mask, D4 the off mask, D7 the ramp rate, D8 the on-level, D10 the
+// non-toggle mask, D11 the LED mask and D13 the non-toggle on/off mask.
+type keypadButtonData [14]byte
+
+func (b keypadButtonData) info(button Group) *KeypadButtonInfo {
+	info := &KeypadButtonInfo{
+		OnMask:   b[2],
+		OffMask:  b[3],
+		RampRate: byteToRampRate(b[6]),
+		OnLevel:  byteToOnLevel(b[7]),
+	}
+
+	bit := buttonBit(button)
+
+	if b[9]&bit != 0 {
+		if b[12]&bit != 0 {
+			info.ToggleMode = ToggleModeOn
+		} else {
+			info.ToggleMode = ToggleModeOff
+		}
+	}
+
+	return info
+}
+
+// buttonBit returns the bit of a button in the keypad masks.
+func buttonBit(button Group) byte {
+	return 1 << (byte(button) - 1)
+}
+
+// keypadButtons implements the button features of keypads.
+type keypadButtons struct {
+	base  *BaseDevice
+	count int
+}
+
+// Buttons returns the number of buttons of the device.
+func (k keypadButtons) Buttons() int {
+	return k.count
+}
+
+// ButtonGroups returns the groups of the buttons of the device.
+//
+// 6-button keypads have their on and off buttons on group 1 and their
+// other buttons on groups 3 to 6.
+func (k keypadButtons) ButtonGroups() []Group {
+	if k.count == 6 {
+		return []Group{1, 3, 4, 5, 6}
+	}
+
+	groups := make([]Group, k.count)
+
+	for i := range groups {
+		groups[i] = Group(i + 1)
+	}
+
+	return groups
+}
+
+// GetLEDMask returns the LED mask of the device, the bit n-1 being the LED
+// of the button on group n.
+func (k keypadButtons) GetLEDMask(ctx context.Context) (byte, error) {
+	value, err := k.base.Cached(ctx, "leds", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		response, err := plm.SendCommand(ctx, k.base.id, NewCommand(commandBytesGetLEDMask))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return response.Ack.CommandBytes[1], nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(byte), nil
+}
+
+// SetLEDMask sets the LED mask of the device.
+func (k keypadButtons) SetLEDMask(ctx context.Context, mask byte) error {
+	return k.setData(ctx, 1, keypadDataSetLEDMask, mask)
+}
+
+// GetButtonInfo returns the settings of a button.
+func (k keypadButtons) GetButtonInfo(ctx context.Context, button Group) (*KeypadButtonInfo, error) {
+	data, err := k.getData(ctx, button)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data.info(button), nil
+}
+
+// SetButtonInfo sets the settings of a button.
+func (k keypadButtons) SetButtonInfo(ctx context.Context, button Group, info KeypadButtonInfo) error {
+	data, err := k.getData(ctx, button)
+
+	if err != nil {
+		return err
+	}
+
+	bit := buttonBit(button)
+	nonToggleMask := data[9] &^ bit
+	nonToggleOnOff := data[12] &^ bit
+
+	switch info.ToggleMode {
+	case ToggleModeOn:
+		nonToggleMask |= bit
+		nonToggleOnOff |= bit
+	case ToggleModeOff:
+		nonToggleMask |= bit
+	}
+
+	for _, set := range []struct {
+		Group Group
+		Code  byte
+		Value byte
+	}{
+		{button, keypadDataSetOnMask, info.OnMask},
+		{button, keypadDataSetOffMask, info.OffMask},
+		{button, keypadDataSetRampRate, rampRateToByte(info.RampRate)},
+		{button, keypadDataSetOnLevel, onLevelToByte(info.OnLevel)},
+		{1, keypadDataSetNonToggleMask, nonToggleMask},
+		{1, keypadDataSetNonToggleOnOff, nonToggleOnOff},
+	} {
+		if err := k.setData(ctx, set.Group, set.Code, set.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (k keypadButtons) checkButton(button Group) error {
+	for _, group := range k.ButtonGroups() {
+		if group == button {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no such button on a %d-button keypad: %d", k.count, button)
+}
+
+func (k keypadButtons) getData(ctx context.Context, button Group) (*keypadButtonData, error) {
+	if err := k.checkButton(button); err != nil {
+		return nil, err
+	}
+
+	value, err := k.base.Cached(ctx, fmt.Sprintf("button-%d", button), func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		command := NewExtendedCommand(commandBytesExtendedGetSet, [14]byte{byte(button)})
+		command.ExtendedResponse = true
+
+		response, err := plm.SendCommand(ctx, k.base.id, command)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Extended == nil {
+			return nil, fmt.Errorf("no data set received for button %d", button)
+		}
+
+		data := keypadButtonData(response.Extended.UserData)
+
+		return &data, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*keypadButtonData), nil
+}
+
+func (k keypadButtons) setData(ctx context.Context, button Group, code byte, value byte) error {
+	_, err := k.base.SendCommand(ctx, NewExtendedCommand(commandBytesExtendedGetSet, [14]byte{byte(button), code, value}))
+
+	return err
+}
+
+type keypad struct {
+	relay
+	keypadButtons
+}
+
+type dimmerKeypad struct {
+	dimmer
+	keypadButtons
+}
+
+// keypadButtonCount returns the number of buttons of a keypad category, or 0
+// if the category is not a keypad one.
+func keypadButtonCount(category Category) int {
+	switch category {
+	case Category{dimmableLightingControl, keypadLincDimmer2486DWH6},
+		Category{dimmableLightingControl, keypadLincDimmer23342Six},
+		Category{switchedLightingControl, keypadLincRelay2487S}:
+		return 6
+	case Category{dimmableLightingControl, keypadLincDimmer2486D},
+		Category{dimmableLightingControl, keypadLincDimmer2486DWH8},
+		Category{dimmableLightingControl, keypadLincDimmer2334232},
+		Category{dimmableLightingControl, keypadLincDimmer23342Eight},
+		Category{switchedLightingControl, keypadLincRelay2486SWH8}:
+		return 8
+	}
+
+	return 0
+}
+
+// keypadDriver is the driver of KeypadLincs.
+//
+// The state and information are the ones of the load.
+type keypadDriver struct {
+	lightDriver
+}
+
+func (keypadDriver) Name() string {
+	return "keypad"
+}
+
+func (keypadDriver) NewDevice(base *BaseDevice) Device {
+	buttons := keypadButtons{base: base, count: keypadButtonCount(base.category)}
+
+	if base.category.MainCategory == switchedLightingControl {
+		return &keypad{relay: relay{base}, keypadButtons: buttons}
+	}
+
+	return &dimmerKeypad{dimmer: dimmer{relay{base}}, keypadButtons: buttons}
+}
+
+// DecodeEvent reports the broadcasts of buttons as button events.
+func (d keypadDriver) DecodeEvent(msg *Message, event *DeviceEvent) bool {
+	if !d.lightDriver.DecodeEvent(msg, event) {
+		return false
+	}
+
+	if event.Group != 0 {
+		event.Type = "button"
+		event.Attributes = map[string]interface{}{
+			"button": int(event.Group),
+			"onoff":  event.OnOff.String(),
+		}
+	}
+
+	return true
+}
+
+func (keypadDriver) Commands() []DeviceCommand {
+	return append([]DeviceCommand{
+		{
+			Name:        "buttons",
+			Description: "Show the groups of the buttons",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				keypad, err := asKeypad(device, args, 0)
+
+				if err != nil {
+					return nil, err
+				}
+
+				var groups []int
+
+				for _, group := range keypad.ButtonGroups() {
+					groups = append(groups, int(group))
+				}
+
+				return groups, nil
+			},
+		},
+		{
+			Name:        "leds",
+			Description: "Show the LED mask",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				keypad, err := asKeypad(device, args, 0)
+
+				if err != nil {
+					return nil, err
+				}
+
+				mask, err := keypad.GetLEDMask(ctx)
+
+				if err != nil {
+					return nil, err
+				}
+
+				return fmt.Sprintf("%08b", mask), nil
+			},
+		},
+		{
+			Name:        "led",
+			Usage:       "<button> <on|off>",
+			Description: "Switch the LED of a button on or off",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				keypad, err := asKeypad(device, args, 2)
+
+				if err != nil {
+					return nil, err
+				}
+
+				button, err := parseButton(args[0])
+
+				if err != nil {
+					return nil, err
+				}
+
+				mask, err := keypad.GetLEDMask(ctx)
+
+				if err != nil {
+					return nil, err
+				}
+
+				switch args[1] {
+				case "on":
+					mask |= buttonBit(button)
+				case "off":
+					mask &^= buttonBit(button)
+				default:
+					return nil, fmt.Errorf("expected on or off but got: %s", args[1])
+				}
+
+				return nil, keypad.SetLEDMask(ctx, mask)
+			},
+		},
+		{
+			Name:        "button",
+			Usage:       "<button>",
+			Description: "Show the settings of a button",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				keypad, err := asKeypad(device, args, 1)
+
+				if err != nil {
+					return nil, err
+				}
+
+				button, err := parseButton(args[0])
+
+				if err != nil {
+					return nil, err
+				}
+
+				return keypad.GetButtonInfo(ctx, button)
+			},
+		},
+		{
+			Name:        "toggle-mode",
+			Usage:       "<button> <toggle|on|off>",
+			Description: "Set the toggle mode of a button",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				return nil, updateButtonInfo(ctx, device, args, func(info *KeypadButtonInfo, value string) error {
+					return info.ToggleMode.UnmarshalText([]byte(value))
+				})
+			},
+		},
+		{
+			Name:        "button-on-level",
+			Usage:       "<button> <level>",
+			Description: "Set the on-level of a button, as a decimal value in the [0, 1] range",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				return nil, updateButtonInfo(ctx, device, args, func(info *KeypadButtonInfo, value string) (err error) {
+					info.OnLevel, err = strconv.ParseFloat(value, 64)
+					return
+				})
+			},
+		},
+		{
+			Name:        "button-ramp-rate",
+			Usage:       "<button> <duration>",
+			Description: "Set the ramp rate of a button",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				return nil, updateButtonInfo(ctx, device, args, func(info *KeypadButtonInfo, value string) (err error) {
+					info.RampRate, err = time.ParseDuration(value)
+					return
+				})
+			},
+		},
+	}, dimmerCommands...)
+}
+
+// asKeypad returns a device as a keypad, after checking the number of
+// arguments of a command.
+func asKeypad(device Device, args []string, count int) (Keypad, error) {
+	keypad, ok := device.(Keypad)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if len(args) != count {
+		return nil, fmt.Errorf("expected %d argument(s) but got %d", count, len(args))
+	}
+
+	return keypad, nil
+}
+
+// updateButtonInfo reads, updates and writes back the settings of a button.
+func updateButtonInfo(ctx context.Context, device Device, args []string, update func(*KeypadButtonInfo, string) error) error {
+	keypad, err := asKeypad(device, args, 2)
+
+	if err != nil {
+		return err
+	}
+
+	button, err := parseButton(args[0])
+
+	if err != nil {
+		return err
+	}
+
+	info, err := keypad.GetButtonInfo(ctx, button)
+
+	if err != nil {
+		return err
+	}
+
+	if err := update(info, args[1]); err != nil {
+		return err
+	}
+
+	return keypad.SetButtonInfo(ctx, button, *info)
+}
+
+func parseButton(s string) (Group, error) {
+	value, err := strconv.ParseUint(s, 10, 8)
+
+	if err != nil || value == 0 || value > 8 {
+		return 0, fmt.Errorf("invalid button: %s", s)
+	}
+
+	return Group(value), nil
+}
+
+func init() {
+	RegisterDeviceDriver(
+		keypadDriver{},
+		Category{dimmableLightingControl, keypadLincDimmer2486D},
+		Category{dimmableLightingControl, keypadLincDimmer2486DWH8},
+		Category{dimmableLightingControl, keypadLincDimmer2486DWH6},
+		Category{dimmableLightingControl, keypadLincDimmer2334232},
+		Category{dimmableLightingControl, keypadLincDimmer23342Eight},
+		Category{dimmableLightingControl, keypadLincDimmer23342Six},
+		Category{switchedLightingControl, keypadLincRelay2486SWH8},
+		Category{switchedLightingControl, keypadLincRelay2487S},
+	)
+}
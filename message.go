@@ -1,6 +1,7 @@
 package insteon
 
 import (
+	"encoding/hex"
 	"fmt"
 	"strings"
 )
@@ -75,6 +76,16 @@ func (m Message) IsAck() bool {
 	return m.Flags&MessageFlagAck != 0
 }
 
+// IsNak returns whether the message is a NAK.
+func (m Message) IsNak() bool {
+	return m.Flags&(MessageFlagAck|MessageFlagBroadcast) == MessageFlagAck|MessageFlagBroadcast
+}
+
+// HopsUsed returns the number of hops the message needed to reach us.
+func (m Message) HopsUsed() int {
+	return m.MaxHops - m.HopsLeft
+}
+
 // IsExtended returns whether the message is an extended message.
 func (m Message) IsExtended() bool {
 	return m.Flags&MessageFlagExtended != 0
@@ -133,6 +144,28 @@ func (m *Message) UnmarshalBinary(b []byte) error {
 	return nil
 }
 
+// MarshalText -
+func (m Message) MarshalText() ([]byte, error) {
+	data, err := m.MarshalBinary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(hex.EncodeToString(data)), nil
+}
+
+// UnmarshalText -
+func (m *Message) UnmarshalText(b []byte) error {
+	data, err := hex.DecodeString(string(b))
+
+	if err != nil {
+		return fmt.Errorf("failed to hex-decode string: %s", err)
+	}
+
+	return m.UnmarshalBinary(data)
+}
+
 func checksum(commandBytes [2]byte, b []byte) byte {
 	checksum := commandBytes[0] + commandBytes[1]
 
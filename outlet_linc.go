@@ -0,0 +1,208 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+)
+
+var (
+	commandBytesGetOutlets = [2]byte{0x19, 0x01}
+)
+
+// OutletLinc is a dual OutletLinc: a device with two outlets that are
+// controlled on their own, the top one as button 1 and the bottom one as
+// button 2.
+//
+// Relay methods control the top outlet.
+type OutletLinc interface {
+	Relay
+	GetOutlets(ctx context.Context) (*OutletLincState, error)
+	IsOutletOn(ctx context.Context, outlet int) (bool, error)
+	SetOutlet(ctx context.Context, outlet int, on bool) error
+}
+
+// OutletLincState represents the state of the outlets of an OutletLinc.
+type OutletLincState struct {
+	Top    bool `json:"top"`
+	Bottom bool `json:"bottom"`
+}
+
+type outletLinc struct {
+	relay
+}
+
+// GetOutlets returns the state of both outlets, read through the status
+// request variant that reports them as a bitmask.
+func (d *outletLinc) GetOutlets(ctx context.Context) (*OutletLincState, error) {
+	value, err := d.Cached(ctx, "outlets", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		response, err := plm.SendCommand(ctx, d.id, NewCommand(commandBytesGetOutlets))
+
+		if err != nil {
+			return nil, err
+		}
+
+		mask := response.Ack.CommandBytes[1]
+
+		return &OutletLincState{
+			Top:    mask&0x01 != 0,
+			Bottom: mask&0x02 != 0,
+		}, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*OutletLincState), nil
+}
+
+// IsOutletOn returns whether an outlet is on.
+func (d *outletLinc) IsOutletOn(ctx context.Context, outlet int) (bool, error) {
+	if err := checkOutlet(outlet); err != nil {
+		return false, err
+	}
+
+	state, err := d.GetOutlets(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	if outlet == 1 {
+		return state.Top, nil
+	}
+
+	return state.Bottom, nil
+}
+
+// SetOutlet switches an outlet on or off, with an extended on or off
+// command that has the outlet in its user data.
+func (d *outletLinc) SetOutlet(ctx context.Context, outlet int, on bool) error {
+	if err := checkOutlet(outlet); err != nil {
+		return err
+	}
+
+	commandBytes := [2]byte{0x13, 0x00}
+
+	if on {
+		commandBytes = [2]byte{0x11, 0xff}
+	}
+
+	_, err := d.SendCommand(ctx, NewExtendedCommand(commandBytes, [14]byte{byte(outlet)}))
+
+	return err
+}
+
+// IsOn returns whether the top outlet is on.
+func (d *outletLinc) IsOn(ctx context.Context) (bool, error) {
+	return d.IsOutletOn(ctx, 1)
+}
+
+// On switches the top outlet on.
+func (d *outletLinc) On(ctx context.Context) error {
+	return d.SetOutlet(ctx, 1, true)
+}
+
+// Off switches the top outlet off.
+func (d *outletLinc) Off(ctx context.Context) error {
+	return d.SetOutlet(ctx, 1, false)
+}
+
+func checkOutlet(outlet int) error {
+	if outlet != 1 && outlet != 2 {
+		return fmt.Errorf("no such outlet: %d", outlet)
+	}
+
+	return nil
+}
+
+// outletLincDriver is the driver of dual OutletLincs.
+type outletLincDriver struct {
+	BaseDeviceDriver
+}
+
+func (outletLincDriver) Name() string {
+	return "outlet"
+}
+
+func (outletLincDriver) NewDevice(base *BaseDevice) Device {
+	return &outletLinc{relay{base}}
+}
+
+func (outletLincDriver) NewState() interface{} {
+	return &OutletLincState{}
+}
+
+func (outletLincDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	outletLinc, ok := device.(OutletLinc)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return outletLinc.GetOutlets(ctx)
+}
+
+func (outletLincDriver) SetState(ctx context.Context, device Device, state interface{}) error {
+	outletLinc, ok := device.(OutletLinc)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	outletLincState, ok := state.(*OutletLincState)
+
+	if !ok {
+		return fmt.Errorf("unexpected state type: %T", state)
+	}
+
+	if err := outletLinc.SetOutlet(ctx, 1, outletLincState.Top); err != nil {
+		return err
+	}
+
+	return outletLinc.SetOutlet(ctx, 2, outletLincState.Bottom)
+}
+
+func (outletLincDriver) Commands() []DeviceCommand {
+	return append([]DeviceCommand{
+		{
+			Name:        "outlet",
+			Usage:       "<1|2> [on|off]",
+			Description: "Show whether an outlet is on, or switch it on or off",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				outletLinc, ok := device.(OutletLinc)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				if len(args) < 1 || len(args) > 2 {
+					return nil, fmt.Errorf("expected an outlet and an optional state")
+				}
+
+				var outlet int
+
+				if _, err := fmt.Sscanf(args[0], "%d", &outlet); err != nil {
+					return nil, fmt.Errorf("invalid outlet: %s", args[0])
+				}
+
+				if len(args) == 1 {
+					return outletLinc.IsOutletOn(ctx, outlet)
+				}
+
+				switch args[1] {
+				case "on":
+					return nil, outletLinc.SetOutlet(ctx, outlet, true)
+				case "off":
+					return nil, outletLinc.SetOutlet(ctx, outlet, false)
+				}
+
+				return nil, fmt.Errorf("expected on or off but got: %s", args[1])
+			},
+		},
+	}, relayCommands...)
+}
+
+func init() {
+	RegisterDeviceDriver(outletLincDriver{}, Category{switchedLightingControl, outletLinc2663})
+}
@@ -8,10 +8,17 @@ import (
 // PacketReader implements a packet reader on top of a regular reader.
 type packetReader struct {
 	reader *bufio.Reader
+	sizes  map[CommandCode]int
 }
 
+// newPacketReader reads packets sent by a PLM.
 func newPacketReader(r io.Reader) packetReader {
-	return packetReader{bufio.NewReader(r)}
+	return packetReader{bufio.NewReader(r), packetSizes}
+}
+
+// newHostPacketReader reads packets sent by a host to a PLM.
+func newHostPacketReader(r io.Reader) packetReader {
+	return packetReader{bufio.NewReader(r), hostPacketSizes}
 }
 
 func (r packetReader) Read() ([]byte, error) {
@@ -35,7 +42,7 @@ func (r packetReader) Read() ([]byte, error) {
 
 		commandCode := CommandCode(b)
 
-		size, ok := packetSizes[commandCode]
+		size, ok := r.sizes[commandCode]
 
 		if !ok {
 			// We didn't find a known command-code: let's ignore this packet
@@ -43,6 +50,8 @@ func (r packetReader) Read() ([]byte, error) {
 			if err = r.reader.UnreadByte(); err != nil {
 				return nil, err
 			}
+
+			continue
 		}
 
 		result = make([]byte, size+2)
@@ -100,4 +109,43 @@ var packetSizes = map[CommandCode]int{
 	cmdStartAllLinking:               3,
 	cmdCancelAllLinking:              1,
 	cmdSendStandardOrExtendedMessage: 7,
+	cmdSendAllLink:                   4,
+	cmdSendX10:                       3,
+	cmdSetHostDeviceCategory:         4,
+	cmdResetIM:                       1,
+	cmdSetAckMessageByte:             2,
+	cmdSetIMConfiguration:            2,
+	cmdGetAllLinkRecordForSender:     1,
+	cmdLedOn:                         1,
+	cmdLedOff:                        1,
+	cmdManageAllLinkRecord:           10,
+	cmdSetNakMessageByte:             2,
+	cmdSetNakMessageTwoBytes:         3,
+	cmdRFSleep:                       1,
+	cmdGetIMConfiguration:            4,
+}
+
+// hostPacketSizes contains the sizes of the packets sent by the host, which
+// have no trailing acknowledgment byte.
+var hostPacketSizes = map[CommandCode]int{
+	cmdGetIMInfo:                     0,
+	cmdSendAllLink:                   3,
+	cmdSendStandardOrExtendedMessage: 6,
+	cmdSendX10:                       2,
+	cmdStartAllLinking:               2,
+	cmdCancelAllLinking:              0,
+	cmdSetHostDeviceCategory:         3,
+	cmdResetIM:                       0,
+	cmdSetAckMessageByte:             1,
+	cmdGetFirstAllLinkRecord:         0,
+	cmdGetNextAllLinkRecord:          0,
+	cmdSetIMConfiguration:            1,
+	cmdGetAllLinkRecordForSender:     0,
+	cmdLedOn:                         0,
+	cmdLedOff:                        0,
+	cmdManageAllLinkRecord:           9,
+	cmdSetNakMessageByte:             1,
+	cmdSetNakMessageTwoBytes:         2,
+	cmdRFSleep:                       0,
+	cmdGetIMConfiguration:            0,
 }
@@ -2,8 +2,12 @@ package insteon
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"strings"
+	"sync"
 )
 
 // PowerLineModem represnts a powerline modem.
@@ -15,22 +19,31 @@ type PowerLineModem interface {
 	GetDeviceInfo(ctx context.Context, identity ID) (deviceInfo *DeviceInfo, err error)
 	SetDeviceInfo(ctx context.Context, identity ID, deviceInfo DeviceInfo) error
 	Beep(ctx context.Context, identity ID) (err error)
+	Ping(ctx context.Context, identity ID) (result *PingResult, err error)
+	GetDeviceCategory(ctx context.Context, identity ID) (category *Category, err error)
+	SendCommand(ctx context.Context, identity ID, command Command) (response *CommandResponse, err error)
 	Monitor(ctx context.Context, events chan<- DeviceEvent) error
 }
 
 // DefaultPowerLineModem is the default PowerLine Modem instance.
-var DefaultPowerLineModem = func() PowerLineModem {
-	plm, err := NewPowerLineModem(PowerLineModemDevice)
+//
+// The underlying device is only opened on first use.
+var DefaultPowerLineModem PowerLineModem = &lazyPowerLineModem{}
 
-	if err != nil {
-		panic(fmt.Errorf("instanciating default PowerLine Modem: %s", err))
+// NewPowerLineModem instantiates a new PowerLine Modem.
+//
+// The special "auto" device detects the PowerLine Modem among the serial
+// ports, and "failover:<device>,<device>,..." combines several redundant
+// PowerLine Modems.
+func NewPowerLineModem(device string) (PowerLineModem, error) {
+	if device == AutoPowerLineModemDevice {
+		return detectPowerLineModem(context.Background())
 	}
 
-	return plm
-}()
+	if strings.HasPrefix(device, failoverScheme) {
+		return newFailoverPowerLineModemFromDevices(strings.TrimPrefix(device, failoverScheme))
+	}
 
-// NewPowerLineModem instantiates a new PowerLine Modem.
-func NewPowerLineModem(device string) (PowerLineModem, error) {
 	url, err := url.Parse(device)
 
 	if err != nil {
@@ -40,9 +53,158 @@ func NewPowerLineModem(device string) (PowerLineModem, error) {
 	switch url.Scheme {
 	case "http", "https":
 		return NewHTTPPowerLineModem(url.String())
-	case "tcp":
-		return NewRemotePowerLineModem(url.Host)
+	case "tcp", "unix":
+		return dialPowerLineModem(url)
+	case "rfc2217":
+		return NewRFC2217PowerLineModem(url)
+	case "tls":
+		return NewTLSPowerLineModem(url)
+	case "hub":
+		return NewInsteonHubPowerLineModem(url)
+	case "dryrun":
+		return NewDryRunPowerLineModem(url)
 	default:
 		return NewLocalPowerLineModem(url.String())
 	}
 }
+
+// lazyPowerLineModem opens the system PowerLine Modem device on first use.
+type lazyPowerLineModem struct {
+	once sync.Once
+	plm  PowerLineModem
+	err  error
+}
+
+func (m *lazyPowerLineModem) get() (PowerLineModem, error) {
+	m.once.Do(func() {
+		if m.plm, m.err = NewPowerLineModem(PowerLineModemDevice); m.err != nil {
+			m.err = fmt.Errorf("instanciating default PowerLine Modem: %s", m.err)
+		}
+	})
+
+	return m.plm, m.err
+}
+
+// Close the system PowerLine Modem device, if it was opened.
+func (m *lazyPowerLineModem) Close() error {
+	// Make sure it does not get opened after being closed.
+	m.once.Do(func() {
+		m.err = errors.New("default PowerLine Modem is closed")
+	})
+
+	if closer, ok := m.plm.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+func (m *lazyPowerLineModem) GetIMInfo(ctx context.Context) (*IMInfo, error) {
+	plm, err := m.get()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plm.GetIMInfo(ctx)
+}
+
+func (m *lazyPowerLineModem) GetAllLinkDB(ctx context.Context) (AllLinkRecordSlice, error) {
+	plm, err := m.get()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plm.GetAllLinkDB(ctx)
+}
+
+func (m *lazyPowerLineModem) GetDeviceState(ctx context.Context, identity ID) (*LightState, error) {
+	plm, err := m.get()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plm.GetDeviceState(ctx, identity)
+}
+
+func (m *lazyPowerLineModem) SetDeviceState(ctx context.Context, identity ID, state LightState) error {
+	plm, err := m.get()
+
+	if err != nil {
+		return err
+	}
+
+	return plm.SetDeviceState(ctx, identity, state)
+}
+
+func (m *lazyPowerLineModem) GetDeviceInfo(ctx context.Context, identity ID) (*DeviceInfo, error) {
+	plm, err := m.get()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plm.GetDeviceInfo(ctx, identity)
+}
+
+func (m *lazyPowerLineModem) SetDeviceInfo(ctx context.Context, identity ID, deviceInfo DeviceInfo) error {
+	plm, err := m.get()
+
+	if err != nil {
+		return err
+	}
+
+	return plm.SetDeviceInfo(ctx, identity, deviceInfo)
+}
+
+func (m *lazyPowerLineModem) Beep(ctx context.Context, identity ID) error {
+	plm, err := m.get()
+
+	if err != nil {
+		return err
+	}
+
+	return plm.Beep(ctx, identity)
+}
+
+func (m *lazyPowerLineModem) Ping(ctx context.Context, identity ID) (*PingResult, error) {
+	plm, err := m.get()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plm.Ping(ctx, identity)
+}
+
+func (m *lazyPowerLineModem) GetDeviceCategory(ctx context.Context, identity ID) (*Category, error) {
+	plm, err := m.get()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plm.GetDeviceCategory(ctx, identity)
+}
+
+func (m *lazyPowerLineModem) SendCommand(ctx context.Context, identity ID, command Command) (*CommandResponse, error) {
+	plm, err := m.get()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return plm.SendCommand(ctx, identity, command)
+}
+
+func (m *lazyPowerLineModem) Monitor(ctx context.Context, events chan<- DeviceEvent) error {
+	plm, err := m.get()
+
+	if err != nil {
+		return err
+	}
+
+	return plm.Monitor(ctx, events)
+}
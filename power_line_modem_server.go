@@ -0,0 +1,231 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+)
+
+// PowerLineModemServer shares a PowerLine Modem with several clients.
+//
+// Clients talk to the server as if it were a PLM: the commands they send are
+// serialized with the other users of the PowerLine Modem and their responses
+// are returned to them only. All the unsolicited traffic is sent to every
+// client.
+type PowerLineModemServer struct {
+	PowerLineModem *SerialPowerLineModem
+
+	// PreSharedKey, if set, is the key that clients must prove they know
+	// before being served.
+	PreSharedKey []byte
+
+	once    sync.Once
+	lock    sync.Mutex
+	clients map[*powerLineModemServerClient]bool
+}
+
+// NewPowerLineModemServer instantiates a new PowerLine Modem server.
+func NewPowerLineModemServer(powerLineModem *SerialPowerLineModem) *PowerLineModemServer {
+	return &PowerLineModemServer{
+		PowerLineModem: powerLineModem,
+	}
+}
+
+// Serve clients accepted on the specified listener for as long as the
+// specified context remains valid.
+func (s *PowerLineModemServer) Serve(ctx context.Context, listener net.Listener) error {
+	s.init(ctx)
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("accepting client: %s", err)
+		}
+
+		go s.serveClient(ctx, conn)
+	}
+}
+
+func (s *PowerLineModemServer) init(ctx context.Context) {
+	s.once.Do(func() {
+		s.clients = map[*powerLineModemServerClient]bool{}
+		s.PowerLineModem.init()
+
+		go s.broadcastLoop(ctx)
+	})
+}
+
+// broadcastLoop sends all the unsolicited traffic to the clients.
+func (s *PowerLineModemServer) broadcastLoop(ctx context.Context) {
+	ctx, cancel := s.PowerLineModem.withInbox(ctx)
+	defer cancel()
+
+	inbox := getInbox(ctx)
+
+	for {
+		select {
+		case p := <-inbox.C:
+			if !isIncomingCommandCode(p.CommandCode) {
+				continue
+			}
+
+			b, err := p.MarshalBinary()
+
+			if err != nil {
+				continue
+			}
+
+			for _, client := range s.getClients() {
+				client.Send(b)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *PowerLineModemServer) getClients() []*powerLineModemServerClient {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	clients := make([]*powerLineModemServerClient, 0, len(s.clients))
+
+	for client := range s.clients {
+		clients = append(clients, client)
+	}
+
+	return clients
+}
+
+func (s *PowerLineModemServer) serveClient(ctx context.Context, conn net.Conn) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if len(s.PreSharedKey) > 0 {
+		if err := pskServerHandshake(conn, s.PreSharedKey); err != nil {
+			fmt.Fprintf(os.Stderr, "Authenticating PowerLine Modem client %s: %s.\n", conn.RemoteAddr(), err)
+			conn.Close()
+
+			return
+		}
+	}
+
+	client := &powerLineModemServerClient{
+		Conn:     conn,
+		outgoing: make(chan []byte, 64),
+	}
+
+	s.lock.Lock()
+	s.clients[client] = true
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.clients, client)
+		s.lock.Unlock()
+
+		conn.Close()
+	}()
+
+	go client.writeLoop(ctx)
+
+	r := newHostPacketReader(conn)
+
+	for {
+		b, err := r.Read()
+
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Reading from PowerLine Modem client %s: %s.\n", conn.RemoteAddr(), err)
+			}
+
+			return
+		}
+
+		p := &packet{
+			CommandCode: CommandCode(b[1]),
+			Payload:     b[2:],
+		}
+
+		// Outgoing messages are expected to have a sender identity.
+		if p.CommandCode == cmdSendStandardOrExtendedMessage {
+			p.Payload = append(make([]byte, 3), p.Payload...)
+		}
+
+		err = s.PowerLineModem.execute(ctx, func(ctx context.Context) error {
+			rp, err := s.PowerLineModem.rawRoundtrip(ctx, p)
+
+			if err != nil {
+				return err
+			}
+
+			// The messages of the clients keep the powerline busy like our
+			// own do.
+			if p.CommandCode == cmdSendStandardOrExtendedMessage && rp.IsAck() {
+				msg := &Message{}
+
+				if msg.UnmarshalBinary(p.Payload) == nil {
+					s.PowerLineModem.shaper.ObserveSent(msg)
+				}
+			}
+
+			b, err := rp.MarshalBinary()
+
+			if err != nil {
+				return err
+			}
+
+			client.Send(b)
+
+			return nil
+		})
+
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+type powerLineModemServerClient struct {
+	Conn net.Conn
+
+	outgoing chan []byte
+}
+
+// Send data to the client.
+//
+// If the client does not keep up, the data is dropped rather than blocking
+// the PowerLine Modem.
+func (c *powerLineModemServerClient) Send(b []byte) {
+	select {
+	case c.outgoing <- b:
+	default:
+	}
+}
+
+func (c *powerLineModemServerClient) writeLoop(ctx context.Context) {
+	for {
+		select {
+		case b := <-c.outgoing:
+			if _, err := c.Conn.Write(b); err != nil {
+				c.Conn.Close()
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
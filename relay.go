@@ -0,0 +1,168 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+)
+
+// Relay is a device that switches a load on and off.
+type Relay interface {
+	Device
+	IsOn(ctx context.Context) (bool, error)
+	On(ctx context.Context) error
+	Off(ctx context.Context) error
+}
+
+type relay struct {
+	*BaseDevice
+}
+
+// IsOn returns whether the load is on.
+func (d *relay) IsOn(ctx context.Context) (bool, error) {
+	state, err := d.getLightState(ctx)
+
+	if err != nil {
+		return false, err
+	}
+
+	return bool(state.OnOff), nil
+}
+
+// On switches the load on.
+func (d *relay) On(ctx context.Context) error {
+	return d.setLightState(ctx, LightState{OnOff: LightOn, Level: 1})
+}
+
+// Off switches the load off.
+func (d *relay) Off(ctx context.Context) error {
+	return d.setLightState(ctx, LightState{OnOff: LightOff})
+}
+
+func (d *BaseDevice) getLightState(ctx context.Context) (*LightState, error) {
+	value, err := d.Cached(ctx, "state", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		return plm.GetDeviceState(ctx, d.id)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*LightState), nil
+}
+
+func (d *BaseDevice) setLightState(ctx context.Context, state LightState) error {
+	return d.Do(ctx, func(ctx context.Context, plm PowerLineModem) error {
+		return plm.SetDeviceState(ctx, d.id, state)
+	})
+}
+
+// lightStateDevice is implemented by the handles of devices that have a
+// light state.
+type lightStateDevice interface {
+	getLightState(ctx context.Context) (*LightState, error)
+	setLightState(ctx context.Context, state LightState) error
+}
+
+// switchDriver is the driver of on/off switches.
+type switchDriver struct {
+	BaseDeviceDriver
+}
+
+func (switchDriver) Name() string {
+	return "switch"
+}
+
+func (switchDriver) NewDevice(base *BaseDevice) Device {
+	return &relay{base}
+}
+
+func (switchDriver) NewState() interface{} {
+	return &LightState{}
+}
+
+func (switchDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	d, ok := device.(lightStateDevice)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return d.getLightState(ctx)
+}
+
+func (switchDriver) SetState(ctx context.Context, device Device, state interface{}) error {
+	d, ok := device.(lightStateDevice)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	lightState, ok := state.(*LightState)
+
+	if !ok {
+		return fmt.Errorf("unexpected state type: %T", state)
+	}
+
+	return d.setLightState(ctx, *lightState)
+}
+
+func (switchDriver) Commands() []DeviceCommand {
+	return relayCommands
+}
+
+var relayCommands = []DeviceCommand{
+	{
+		Name:        "on",
+		Description: "Switch the load on",
+		Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+			relay, err := asRelay(device)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, relay.On(ctx)
+		},
+	},
+	{
+		Name:        "off",
+		Description: "Switch the load off",
+		Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+			relay, err := asRelay(device)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, relay.Off(ctx)
+		},
+	},
+	{
+		Name:        "beep",
+		Description: "Make the device beep",
+		Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+			return nil, device.Beep(ctx)
+		},
+	},
+}
+
+// asRelay returns a device as a relay.
+//
+// Fans are handled as their light.
+func asRelay(device Device) (Relay, error) {
+	if fan, ok := device.(Fan); ok {
+		return fan.Light(), nil
+	}
+
+	relay, ok := device.(Relay)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return relay, nil
+}
+
+func init() {
+	RegisterMainCategoryDeviceDriver(switchDriver{}, switchedLightingControl)
+}
@@ -0,0 +1,180 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+)
+
+// The actions reported for the buttons of remotes.
+const (
+	ButtonPressed       = "pressed"
+	ButtonDoublePressed = "double-pressed"
+	ButtonHeld          = "held"
+	ButtonReleased      = "released"
+)
+
+// Remote is a controller-only device, like a mini remote, that reports the
+// actions on its buttons as broadcasts on their groups.
+//
+// Remotes sleep like sensors: their last action and battery level are in
+// their attributes and their last broadcast tells when they were last seen.
+type Remote interface {
+	Sensor
+	ButtonGroups() []Group
+	RequestBatteryLevel() <-chan error
+}
+
+type remote struct {
+	sensor
+	buttons int
+}
+
+// ButtonGroups returns the groups of the buttons of the remote.
+func (d *remote) ButtonGroups() []Group {
+	groups := make([]Group, d.buttons)
+
+	for i := range groups {
+		groups[i] = Group(i + 1)
+	}
+
+	return groups
+}
+
+// RequestBatteryLevel queues a request for the battery level of the remote,
+// which is reported in D10 of its extended data set, as the battery_level
+// attribute.
+func (d *remote) RequestBatteryLevel() <-chan error {
+	return d.Enqueue("battery", func(ctx context.Context, plm PowerLineModem) error {
+		command := NewExtendedCommand(commandBytesExtendedGetSet, [14]byte{0x01})
+		command.ExtendedResponse = true
+
+		response, err := plm.SendCommand(ctx, d.id, command)
+
+		if err != nil {
+			return err
+		}
+
+		if response.Extended == nil {
+			return fmt.Errorf("no data set received from %s", d.id)
+		}
+
+		d.devices.setAttributes(d.id, map[string]interface{}{
+			"battery_level": int(response.Extended.UserData[9]),
+		})
+
+		return nil
+	})
+}
+
+// remoteDriver is the driver of remotes.
+type remoteDriver struct {
+	sensorDriver
+	buttons int
+}
+
+func (d remoteDriver) NewDevice(base *BaseDevice) Device {
+	return &remote{sensor: sensor{base}, buttons: d.buttons}
+}
+
+// DecodeEvent decodes the actions on the buttons of remotes.
+func (d remoteDriver) DecodeEvent(msg *Message, event *DeviceEvent) bool {
+	if !d.sensorDriver.DecodeEvent(msg, event) {
+		return false
+	}
+
+	if event.Group < 1 || int(event.Group) > d.buttons {
+		return true
+	}
+
+	attributes := map[string]interface{}{
+		"button": int(event.Group),
+	}
+
+	switch event.Change {
+	case ChangeInstant:
+		event.Type = ButtonDoublePressed
+	case ChangeStart:
+		event.Type = ButtonHeld
+	case ChangeStop:
+		event.Type = ButtonReleased
+	default:
+		event.Type = ButtonPressed
+	}
+
+	attributes["action"] = event.Type
+
+	if event.Type != ButtonReleased {
+		attributes["onoff"] = bool(event.OnOff)
+	}
+
+	event.Attributes = attributes
+
+	return true
+}
+
+func (d remoteDriver) Commands() []DeviceCommand {
+	return append([]DeviceCommand{
+		{
+			Name:        "battery",
+			Description: "Queue a request for the battery level until the remote wakes up",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				remote, ok := device.(Remote)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				remote.RequestBatteryLevel()
+
+				return remote.PendingCommands(), nil
+			},
+		},
+	}, d.sensorDriver.Commands()...)
+}
+
+// newRemoteDriver returns the driver of remotes with the specified number
+// of buttons.
+func newRemoteDriver(buttons int) remoteDriver {
+	return remoteDriver{
+		sensorDriver: sensorDriver{
+			name: "remote",
+			groups: map[Group]sensorGroup{
+				0x0a: heartbeatSensorGroup,
+			},
+		},
+		buttons: buttons,
+	}
+}
+
+func init() {
+	// Only register actual remotes: devices of unknown categories, reported
+	// as the zero category, must keep the default driver.
+	RegisterDeviceDriver(
+		newRemoteDriver(8),
+		Category{generalizedControllers, remoteLinc2Keypad8Scene},
+		Category{generalizedControllers, miniRemote8Scene},
+		Category{generalizedControllers, miniRemote8Scene869MHz},
+		Category{generalizedControllers, miniRemote8Scene921MHz},
+	)
+
+	RegisterDeviceDriver(
+		newRemoteDriver(6),
+		Category{generalizedControllers, remoteLinc2440},
+	)
+
+	RegisterDeviceDriver(
+		newRemoteDriver(4),
+		Category{generalizedControllers, remoteLinc2Keypad4Scene},
+		Category{generalizedControllers, miniRemote4Scene},
+		Category{generalizedControllers, miniRemote4Scene869MHz},
+		Category{generalizedControllers, miniRemote4Scene921MHz},
+	)
+
+	RegisterDeviceDriver(
+		newRemoteDriver(1),
+		Category{generalizedControllers, remoteLinc2Switch},
+		Category{generalizedControllers, miniRemoteSwitch},
+		Category{generalizedControllers, miniRemoteSwitch869MHz},
+		Category{generalizedControllers, miniRemoteSwitch921MHz},
+	)
+}
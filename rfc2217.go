@@ -0,0 +1,302 @@
+package insteon
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// Telnet commands and options, as defined in RFC 854, RFC 856, RFC 858 and
+// RFC 2217.
+const (
+	telnetSE   byte = 240
+	telnetSB   byte = 250
+	telnetWILL byte = 251
+	telnetWONT byte = 252
+	telnetDO   byte = 253
+	telnetDONT byte = 254
+	telnetIAC  byte = 255
+
+	telnetOptionBinary  byte = 0
+	telnetOptionSGA     byte = 3
+	telnetOptionComPort byte = 44
+
+	comPortSetBaudRate byte = 1
+	comPortSetDataSize byte = 2
+	comPortSetParity   byte = 3
+	comPortSetStopSize byte = 4
+	comPortSetControl  byte = 5
+
+	comPortParityNone       byte = 1
+	comPortStopSizeOne      byte = 1
+	comPortControlNoFlowCtl byte = 1
+)
+
+// NewRFC2217PowerLineModem instantiates a new remote PowerLine Modem exposed
+// through a Telnet COM-port control (RFC 2217) server.
+//
+// The URL must be of the form rfc2217://host:port and accepts an optional
+// baud query parameter.
+func NewRFC2217PowerLineModem(u *url.URL) (*SerialPowerLineModem, error) {
+	baudRate := uint32(19200)
+
+	if value := u.Query().Get("baud"); value != "" {
+		v, err := strconv.ParseUint(value, 10, 32)
+
+		if err != nil {
+			return nil, fmt.Errorf("parsing baud rate: %s", err)
+		}
+
+		baudRate = uint32(v)
+	}
+
+	dial := func() (io.ReadWriteCloser, error) {
+		conn, err := net.Dial("tcp", u.Host)
+
+		if err != nil {
+			return nil, fmt.Errorf("opening remote serial port: %s", err)
+		}
+
+		device := newRFC2217ReadWriteCloser(conn)
+
+		if err = device.negotiate(baudRate); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("negotiating remote serial port settings: %s", err)
+		}
+
+		return device, nil
+	}
+
+	device, err := dial()
+
+	if err != nil {
+		return nil, err
+	}
+
+	m := newSerialPowerLineModem(device)
+	m.Reopen = dial
+
+	return m, nil
+}
+
+// rfc2217ReadWriteCloser presents a clean byte stream over a Telnet
+// connection, handling the option negotiation and IAC escaping.
+type rfc2217ReadWriteCloser struct {
+	Conn net.Conn
+
+	reader    *bufio.Reader
+	writeLock sync.Mutex
+	options   map[[2]byte]bool
+}
+
+func newRFC2217ReadWriteCloser(conn net.Conn) *rfc2217ReadWriteCloser {
+	return &rfc2217ReadWriteCloser{
+		Conn:    conn,
+		reader:  bufio.NewReader(conn),
+		options: map[[2]byte]bool{},
+	}
+}
+
+// negotiate the Telnet options and the serial port settings.
+func (d *rfc2217ReadWriteCloser) negotiate(baudRate uint32) error {
+	for _, option := range [][2]byte{
+		{telnetWILL, telnetOptionBinary},
+		{telnetDO, telnetOptionBinary},
+		{telnetWILL, telnetOptionSGA},
+		{telnetDO, telnetOptionSGA},
+		{telnetWILL, telnetOptionComPort},
+	} {
+		if err := d.sendOption(option[0], option[1]); err != nil {
+			return err
+		}
+	}
+
+	baudRateBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(baudRateBytes, baudRate)
+
+	for _, setting := range [][]byte{
+		append([]byte{comPortSetBaudRate}, baudRateBytes...),
+		{comPortSetDataSize, 8},
+		{comPortSetParity, comPortParityNone},
+		{comPortSetStopSize, comPortStopSizeOne},
+		{comPortSetControl, comPortControlNoFlowCtl},
+	} {
+		if err := d.sendSubnegotiation(telnetOptionComPort, setting); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Read data bytes, stripping all the Telnet commands.
+func (d *rfc2217ReadWriteCloser) Read(buf []byte) (int, error) {
+	n := 0
+
+	for n < len(buf) {
+		// Don't block if we already have something to return.
+		if n > 0 && d.reader.Buffered() == 0 {
+			break
+		}
+
+		b, err := d.reader.ReadByte()
+
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+
+			return 0, err
+		}
+
+		if b != telnetIAC {
+			buf[n] = b
+			n++
+			continue
+		}
+
+		if b, err = d.reader.ReadByte(); err != nil {
+			return n, err
+		}
+
+		switch b {
+		case telnetIAC:
+			// An escaped 0xff data byte.
+			buf[n] = telnetIAC
+			n++
+		case telnetDO, telnetDONT, telnetWILL, telnetWONT:
+			option, err := d.reader.ReadByte()
+
+			if err != nil {
+				return n, err
+			}
+
+			if err = d.handleOption(b, option); err != nil {
+				return n, err
+			}
+		case telnetSB:
+			// Server notifications (line and modem state) are of no
+			// interest to us.
+			if err = d.skipSubnegotiation(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Write data bytes, escaping the IAC bytes.
+func (d *rfc2217ReadWriteCloser) Write(buf []byte) (int, error) {
+	escaped := make([]byte, 0, len(buf))
+
+	for _, b := range buf {
+		if b == telnetIAC {
+			escaped = append(escaped, telnetIAC)
+		}
+
+		escaped = append(escaped, b)
+	}
+
+	if err := d.write(escaped); err != nil {
+		return 0, err
+	}
+
+	return len(buf), nil
+}
+
+// Close the connection.
+func (d *rfc2217ReadWriteCloser) Close() error {
+	return d.Conn.Close()
+}
+
+func (d *rfc2217ReadWriteCloser) handleOption(command byte, option byte) error {
+	supported := option == telnetOptionBinary || option == telnetOptionSGA || option == telnetOptionComPort
+
+	switch command {
+	case telnetDO:
+		if supported {
+			return d.sendOption(telnetWILL, option)
+		}
+
+		return d.sendOption(telnetWONT, option)
+	case telnetWILL:
+		if supported && option != telnetOptionComPort {
+			return d.sendOption(telnetDO, option)
+		}
+
+		return d.sendOption(telnetDONT, option)
+	case telnetDONT:
+		return d.sendOption(telnetWONT, option)
+	case telnetWONT:
+		return d.sendOption(telnetDONT, option)
+	}
+
+	return nil
+}
+
+// sendOption sends an option command, unless it was already sent, to avoid
+// negotiation loops.
+func (d *rfc2217ReadWriteCloser) sendOption(command byte, option byte) error {
+	d.writeLock.Lock()
+	key := [2]byte{command, option}
+	sent := d.options[key]
+	d.options[key] = true
+	d.writeLock.Unlock()
+
+	if sent {
+		return nil
+	}
+
+	return d.write([]byte{telnetIAC, command, option})
+}
+
+func (d *rfc2217ReadWriteCloser) sendSubnegotiation(option byte, data []byte) error {
+	b := []byte{telnetIAC, telnetSB, option}
+
+	for _, x := range data {
+		if x == telnetIAC {
+			b = append(b, telnetIAC)
+		}
+
+		b = append(b, x)
+	}
+
+	return d.write(append(b, telnetIAC, telnetSE))
+}
+
+func (d *rfc2217ReadWriteCloser) skipSubnegotiation() error {
+	for {
+		b, err := d.reader.ReadByte()
+
+		if err != nil {
+			return err
+		}
+
+		if b != telnetIAC {
+			continue
+		}
+
+		if b, err = d.reader.ReadByte(); err != nil {
+			return err
+		}
+
+		if b == telnetSE {
+			return nil
+		}
+	}
+}
+
+func (d *rfc2217ReadWriteCloser) write(b []byte) error {
+	d.writeLock.Lock()
+	defer d.writeLock.Unlock()
+
+	_, err := d.Conn.Write(b)
+
+	return err
+}
@@ -0,0 +1,364 @@
+package insteon
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var commandBytesReadWriteALDB = [2]byte{0x2f, 0x00}
+
+// aldbWrite is the request code of ALDB writes in the user data of the
+// read/write ALDB command.
+const aldbWrite byte = 0x02
+
+// Sensor is a battery-powered device that sleeps most of the time and only
+// reports through broadcasts.
+//
+// Its state is only known through Devices.Monitor, which also sends the
+// commands queued for it when it wakes up.
+type Sensor interface {
+	Device
+	LastEvent() *DeviceEvent
+	LastSeen() time.Time
+	Attributes() map[string]interface{}
+	Alarms() []Alarm
+	AcknowledgeAlarms()
+	ClearAlarms() []Alarm
+	Enqueue(name string, fn func(context.Context, PowerLineModem) error) <-chan error
+	PendingCommands() []string
+	LastCommandError() error
+	QueueInfo(deviceInfo DeviceInfo) <-chan error
+	QueueAllLinkRecord(address uint16, record AllLinkRecord) <-chan error
+}
+
+// SensorState is the state of a sensor.
+//
+// Attributes holds the last reported value of each of the attributes of
+// the sensor, like motion or low_battery. LastCommandError is the error of
+// the last queued command sent to it, if it failed.
+type SensorState struct {
+	Attributes       map[string]interface{} `json:"attributes,omitempty"`
+	Alarms           []Alarm                `json:"alarms,omitempty"`
+	LastEvent        *DeviceEvent           `json:"last_event,omitempty"`
+	LastSeen         *time.Time             `json:"last_seen,omitempty"`
+	PendingCommands  []string               `json:"pending_commands,omitempty"`
+	LastCommandError string                 `json:"last_command_error,omitempty"`
+}
+
+type sensor struct {
+	*BaseDevice
+}
+
+// LastEvent returns the last event received from the sensor, if any.
+func (d *sensor) LastEvent() *DeviceEvent {
+	event, _ := d.BaseDevice.LastEvent()
+
+	return event
+}
+
+// LastSeen returns the last time an event was received from the sensor.
+func (d *sensor) LastSeen() time.Time {
+	_, timestamp := d.BaseDevice.LastEvent()
+
+	return timestamp
+}
+
+// QueueInfo queues the setting of the information of the sensor.
+func (d *sensor) QueueInfo(deviceInfo DeviceInfo) <-chan error {
+	return d.Enqueue("set info", func(ctx context.Context, plm PowerLineModem) error {
+		return plm.SetDeviceInfo(ctx, d.id, deviceInfo)
+	})
+}
+
+// QueueAllLinkRecord queues the writing of a record in the ALDB of the
+// sensor, at the specified address.
+func (d *sensor) QueueAllLinkRecord(address uint16, record AllLinkRecord) <-chan error {
+	name := fmt.Sprintf("write-aldb %04x", address)
+
+	return d.Enqueue(name, func(ctx context.Context, plm PowerLineModem) error {
+		b, err := record.MarshalBinary()
+
+		if err != nil {
+			return err
+		}
+
+		userData := [14]byte{0x00, aldbWrite, byte(address >> 8), byte(address), byte(len(b))}
+		copy(userData[5:], b)
+
+		_, err = plm.SendCommand(ctx, d.id, NewExtendedCommand(commandBytesReadWriteALDB, userData))
+
+		return err
+	})
+}
+
+// sensorGroup describes what a sensor reports on one of its groups.
+//
+// The attribute is set to OnValue when the group is turned on and to its
+// opposite when it is turned off, and the attributes in Clears are set to
+// false when it is turned on. Groups without attributes only report events.
+//
+// The attributes of alarm groups are alarm conditions, which are latched.
+type sensorGroup struct {
+	Type      string
+	Attribute string
+	OnValue   bool
+	Clears    []string
+	Alarm     bool
+}
+
+var (
+	heartbeatSensorGroup = sensorGroup{Type: "heartbeat"}
+	batterySensorGroup   = sensorGroup{Type: "battery", Attribute: "low_battery", OnValue: true, Alarm: true}
+)
+
+// sensorDriver is the driver of battery-powered sensors, which report on
+// several groups.
+type sensorDriver struct {
+	BaseDeviceDriver
+	name   string
+	groups map[Group]sensorGroup
+}
+
+func (d sensorDriver) Name() string {
+	return d.name
+}
+
+func (sensorDriver) NewDevice(base *BaseDevice) Device {
+	return &sensor{base}
+}
+
+// DecodeEvent decodes the group broadcasts of sensors.
+func (d sensorDriver) DecodeEvent(msg *Message, event *DeviceEvent) bool {
+	if !d.BaseDeviceDriver.DecodeEvent(msg, event) {
+		return false
+	}
+
+	group, ok := d.groups[event.Group]
+
+	if !ok {
+		return true
+	}
+
+	event.Type = group.Type
+	event.Alarm = group.Alarm
+
+	if group.Attribute != "" || len(group.Clears) > 0 {
+		event.Attributes = map[string]interface{}{}
+	}
+
+	if group.Attribute != "" {
+		event.Attributes[group.Attribute] = bool(event.OnOff) == group.OnValue
+	}
+
+	if event.OnOff == LightOn {
+		for _, attribute := range group.Clears {
+			event.Attributes[attribute] = false
+		}
+	}
+
+	return true
+}
+
+func (sensorDriver) NewState() interface{} {
+	return &SensorState{}
+}
+
+func (sensorDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	sensor, ok := device.(Sensor)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	state := &SensorState{
+		Attributes:      sensor.Attributes(),
+		Alarms:          sensor.Alarms(),
+		LastEvent:       sensor.LastEvent(),
+		PendingCommands: sensor.PendingCommands(),
+	}
+
+	if err := sensor.LastCommandError(); err != nil {
+		state.LastCommandError = err.Error()
+	}
+
+	if lastSeen := sensor.LastSeen(); !lastSeen.IsZero() {
+		state.LastSeen = &lastSeen
+	}
+
+	return state, nil
+}
+
+func (sensorDriver) NewInfo() interface{} {
+	return &DeviceInfo{}
+}
+
+// SetInfo queues the setting of the information of a sensor, until it wakes
+// up.
+func (sensorDriver) SetInfo(ctx context.Context, device Device, info interface{}) error {
+	sensor, ok := device.(Sensor)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	deviceInfo, ok := info.(*DeviceInfo)
+
+	if !ok {
+		return fmt.Errorf("unexpected info type: %T", info)
+	}
+
+	sensor.QueueInfo(*deviceInfo)
+
+	return nil
+}
+
+func (sensorDriver) Commands() []DeviceCommand {
+	return []DeviceCommand{
+		{
+			Name:        "send",
+			Usage:       "<command bytes> [<user data>]",
+			Description: "Queue a raw command, in hexadecimal, until the sensor wakes up",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				sensor, ok := device.(Sensor)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				command, err := parseCommand(args)
+
+				if err != nil {
+					return nil, err
+				}
+
+				sensor.Enqueue(fmt.Sprintf("send %s", args[0]), func(ctx context.Context, plm PowerLineModem) error {
+					_, err := plm.SendCommand(ctx, sensor.ID(), command)
+
+					return err
+				})
+
+				return sensor.PendingCommands(), nil
+			},
+		},
+		{
+			Name:        "write-aldb",
+			Usage:       "<address> <record>",
+			Description: "Queue the writing of an ALDB record, in hexadecimal, until the sensor wakes up",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				sensor, ok := device.(Sensor)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				if len(args) != 2 {
+					return nil, fmt.Errorf("expected an address and a record")
+				}
+
+				address, err := strconv.ParseUint(args[0], 16, 16)
+
+				if err != nil {
+					return nil, fmt.Errorf("invalid address: %s", args[0])
+				}
+
+				b, err := hex.DecodeString(args[1])
+
+				if err != nil {
+					return nil, fmt.Errorf("invalid record: %s", args[1])
+				}
+
+				record := AllLinkRecord{}
+
+				if err := record.UnmarshalBinary(b); err != nil {
+					return nil, fmt.Errorf("invalid record: %s", err)
+				}
+
+				sensor.QueueAllLinkRecord(uint16(address), record)
+
+				return sensor.PendingCommands(), nil
+			},
+		},
+	}
+}
+
+// parseCommand parses a raw command from its command bytes and optional
+// user data, in hexadecimal.
+func parseCommand(args []string) (command Command, err error) {
+	if len(args) < 1 || len(args) > 2 {
+		return command, fmt.Errorf("expected command bytes and optional user data")
+	}
+
+	b, err := hex.DecodeString(args[0])
+
+	if err != nil || len(b) != 2 {
+		return command, fmt.Errorf("invalid command bytes: %s", args[0])
+	}
+
+	copy(command.CommandBytes[:], b)
+
+	if len(args) == 2 {
+		b, err = hex.DecodeString(args[1])
+
+		if err != nil || len(b) > 14 {
+			return command, fmt.Errorf("invalid user data: %s", args[1])
+		}
+
+		command.UserData = &[14]byte{}
+		copy(command.UserData[:], b)
+	}
+
+	return command, nil
+}
+
+func init() {
+	RegisterMainCategoryDeviceDriver(sensorDriver{
+		name: "sensor",
+		groups: map[Group]sensorGroup{
+			4: heartbeatSensorGroup,
+		},
+	}, securityHealthSafety)
+
+	RegisterDeviceDriver(sensorDriver{
+		name: "motion-sensor",
+		groups: map[Group]sensorGroup{
+			1: {Type: "motion", Attribute: "motion", OnValue: true},
+			2: {Type: "light", Attribute: "dark", OnValue: true},
+			3: batterySensorGroup,
+			4: heartbeatSensorGroup,
+		},
+	}, Category{securityHealthSafety, motionSensor2842}, Category{securityHealthSafety, motionSensor2844})
+
+	RegisterDeviceDriver(sensorDriver{
+		name: "open-close-sensor",
+		groups: map[Group]sensorGroup{
+			1: {Type: "contact", Attribute: "open", OnValue: true},
+			3: batterySensorGroup,
+			4: heartbeatSensorGroup,
+		},
+	}, Category{securityHealthSafety, openCloseSensor2843}, Category{securityHealthSafety, hiddenDoorSensor2845})
+
+	RegisterDeviceDriver(sensorDriver{
+		name: "leak-sensor",
+		groups: map[Group]sensorGroup{
+			1: {Type: "leak", Attribute: "wet", OnValue: false, Alarm: true},
+			2: {Type: "leak", Attribute: "wet", OnValue: true, Alarm: true},
+			4: {Type: "heartbeat", Attribute: "wet", OnValue: false, Alarm: true},
+		},
+	}, Category{securityHealthSafety, leakSensor2852})
+
+	RegisterDeviceDriver(sensorDriver{
+		name: "smoke-bridge",
+		groups: map[Group]sensorGroup{
+			1:    {Type: "smoke", Attribute: "smoke", OnValue: true, Alarm: true},
+			2:    {Type: "co", Attribute: "co", OnValue: true, Alarm: true},
+			3:    {Type: "test"},
+			5:    {Type: "clear", Clears: []string{"smoke", "co"}, Alarm: true},
+			6:    batterySensorGroup,
+			7:    {Type: "malfunction", Attribute: "malfunction", OnValue: true, Alarm: true},
+			0x0a: heartbeatSensorGroup,
+		},
+	}, Category{securityHealthSafety, smokeBridge2982})
+}
@@ -23,13 +23,35 @@ type SerialPowerLineModem struct {
 
 	ExecutionTimeout time.Duration
 
-	once          sync.Once
-	ctx           context.Context
-	cancel        func()
-	routines      chan func()
-	noWriteBefore time.Time
-	lock          sync.Mutex
-	inboxes       []*inbox
+	// Reopen, if set, reopens the device after it was disconnected. The new
+	// device gets the same debug and fault injection settings as the first
+	// one.
+	Reopen func() (io.ReadWriteCloser, error)
+
+	// Hops learns the number of hops needed to reach each device.
+	//
+	// If nil, no values are persisted.
+	Hops *HopsTracker
+
+	// MaxAttempts is the number of times a message is sent to a device
+	// before giving up. Each new attempt uses one more hop. Relative
+	// commands, like dim steps, are only sent once.
+	MaxAttempts int
+
+	// DisableTrafficShaping disables the holding back of writes while the
+	// powerline is busy with the traffic of other devices.
+	DisableTrafficShaping bool
+
+	once     sync.Once
+	ctx      context.Context
+	cancel   func()
+	routines chan func()
+	closed   bool
+	closing  sync.RWMutex
+	lock     sync.Mutex
+	inboxes  []*inbox
+	shaper   trafficShaper
+	release  func()
 }
 
 // NewLocalPowerLineModem instantiates a new local PowerLine Modem.
@@ -42,24 +64,28 @@ func NewLocalPowerLineModem(serialPort string) (*SerialPowerLineModem, error) {
 		MinimumReadSize: 1,
 	}
 
+	// Make sure we are the only ones using the port.
+	release, err := lockDevice(serialPort)
+
+	if err != nil {
+		return nil, err
+	}
+
 	// Open the port.
 	var device io.ReadWriteCloser
-	var err error
 
 	if device, err = serial.Open(options); err != nil {
+		release()
 		return nil, fmt.Errorf("opening local serial port: %s", err)
 	}
 
-	if PowerLineModemDebug {
-		device = debugReadWriteCloser{
-			ReadWriteCloser: device,
-			DebugWriter:     os.Stderr,
-		}
+	m := newSerialPowerLineModem(device)
+	m.release = release
+	m.Reopen = func() (io.ReadWriteCloser, error) {
+		return serial.Open(options)
 	}
 
-	return &SerialPowerLineModem{
-		Device: device,
-	}, nil
+	return m, nil
 }
 
 // NewRemotePowerLineModem instantiates a new remote PowerLine Modem.
@@ -71,6 +97,37 @@ func NewRemotePowerLineModem(host string) (*SerialPowerLineModem, error) {
 		return nil, fmt.Errorf("opening remote serial port: %s", err)
 	}
 
+	m := newSerialPowerLineModem(device)
+	m.Reopen = func() (io.ReadWriteCloser, error) {
+		return net.Dial("tcp", host)
+	}
+
+	return m, nil
+}
+
+func newSerialPowerLineModem(device io.ReadWriteCloser) *SerialPowerLineModem {
+	return &SerialPowerLineModem{
+		Device: wrapPowerLineModemDevice(device, 0),
+		Hops:   PowerLineModemHops,
+	}
+}
+
+// wrapPowerLineModemDevice applies the debug and fault injection settings to
+// the n-th connection to a device.
+func wrapPowerLineModemDevice(device io.ReadWriteCloser, n int) io.ReadWriteCloser {
+	if PowerLineModemFaults != nil {
+		scenario := *PowerLineModemFaults
+
+		// Replaying the same faults on every connection could disconnect it
+		// at the same point forever.
+		scenario.Seed += int64(n)
+
+		device = &faultInjectionReadWriteCloser{
+			ReadWriteCloser: device,
+			Scenario:        scenario,
+		}
+	}
+
 	if PowerLineModemDebug {
 		device = debugReadWriteCloser{
 			ReadWriteCloser: device,
@@ -78,9 +135,32 @@ func NewRemotePowerLineModem(host string) (*SerialPowerLineModem, error) {
 		}
 	}
 
-	return &SerialPowerLineModem{
-		Device: device,
-	}, nil
+	return device
+}
+
+// Close the PowerLine Modem and its underlying device.
+func (m *SerialPowerLineModem) Close() error {
+	m.init()
+	m.cancel()
+
+	// The pending executions return as soon as the context is cancelled, so
+	// the routines can be stopped safely.
+	m.closing.Lock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.routines)
+	}
+
+	m.closing.Unlock()
+
+	err := m.getDevice().Close()
+
+	if m.release != nil {
+		m.release()
+	}
+
+	return err
 }
 
 // GetIMInfo gets information about the PowerLine Modem.
@@ -101,8 +181,6 @@ func (m *SerialPowerLineModem) GetAllLinkDB(ctx context.Context) (records AllLin
 	m.init()
 
 	err = m.execute(ctx, func(ctx context.Context) error {
-		ctx = withWriteDelay(ctx, time.Millisecond*10)
-
 		p, err := m.rawRoundtrip(ctx, &packet{CommandCode: cmdGetFirstAllLinkRecord})
 
 		if err != nil {
@@ -149,19 +227,39 @@ func (m *SerialPowerLineModem) GetAllLinkDB(ctx context.Context) (records AllLin
 	return
 }
 
-// GetDeviceState gets the on level of a device.
-func (m *SerialPowerLineModem) GetDeviceState(ctx context.Context, identity ID) (state *LightState, err error) {
+// hasLinks tells whether the All-Link DB has records, reading only the
+// first one.
+func (m *SerialPowerLineModem) hasLinks(ctx context.Context) (links bool, err error) {
 	m.init()
 
 	err = m.execute(ctx, func(ctx context.Context) error {
-		msg := newMessage(identity, commandBytesStatusRequest)
-		_, err := m.messageRoundtrip(ctx, msg)
+		p, err := m.rawRoundtrip(ctx, &packet{CommandCode: cmdGetFirstAllLinkRecord})
 
 		if err != nil {
 			return err
 		}
 
-		rmsg, err := m.readStandardMessage(ctx)
+		// A NAK indicates that the DB is empty.
+		if p.IsNak() {
+			return nil
+		}
+
+		links = true
+		_, err = m.readPacketTo(ctx, cmdAllLinkRecordMessage, &AllLinkRecord{})
+
+		return err
+	})
+
+	return
+}
+
+// GetDeviceState gets the on level of a device.
+func (m *SerialPowerLineModem) GetDeviceState(ctx context.Context, identity ID) (state *LightState, err error) {
+	m.init()
+
+	err = m.execute(ctx, func(ctx context.Context) error {
+		msg := newMessage(identity, commandBytesStatusRequest)
+		rmsg, err := m.messageRoundtrip(ctx, msg)
 
 		if err != nil {
 			return err
@@ -336,9 +434,109 @@ func (m *SerialPowerLineModem) Beep(ctx context.Context, identity ID) (err error
 	return
 }
 
+// Ping sends an ID request to a device and measures how long it takes to
+// get its acknowledgment.
+//
+// Unlike other commands, a ping is never retried so that losses can be
+// measured.
+func (m *SerialPowerLineModem) Ping(ctx context.Context, identity ID) (result *PingResult, err error) {
+	m.init()
+
+	err = m.execute(ctx, func(ctx context.Context) error {
+		msg := newMessage(identity, commandBytesIDRequest)
+		hops := m.Hops.Hops(identity)
+		msg.MaxHops = hops
+		msg.HopsLeft = hops
+
+		start := time.Now()
+		ack, err := m.messageAttempt(ctx, msg)
+
+		if err != nil {
+			m.Hops.Record(identity, false, 0)
+
+			return err
+		}
+
+		m.Hops.Record(identity, true, ack.HopsUsed())
+
+		result = &PingResult{
+			Latency:  time.Since(start),
+			Hops:     hops,
+			HopsUsed: ack.HopsUsed(),
+		}
+
+		if ack.IsNak() {
+			reason := NakReason(ack.CommandBytes[1])
+			result.Nak = &reason
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// GetDeviceCategory gets the category of a device.
+//
+// The device answers an ID request with a broadcast that contains its
+// category.
+func (m *SerialPowerLineModem) GetDeviceCategory(ctx context.Context, identity ID) (category *Category, err error) {
+	m.init()
+
+	err = m.execute(ctx, func(ctx context.Context) error {
+		msg := newMessage(identity, commandBytesIDRequest)
+
+		if _, err := m.messageRoundtrip(ctx, msg); err != nil {
+			return err
+		}
+
+		rmsg, err := m.readSetButtonPressed(ctx, identity)
+
+		if err != nil {
+			return err
+		}
+
+		category = &Category{}
+
+		return category.UnmarshalBinary(rmsg.Target[:2])
+	})
+
+	return
+}
+
+// SendCommand sends a raw command to a device.
+func (m *SerialPowerLineModem) SendCommand(ctx context.Context, identity ID, command Command) (response *CommandResponse, err error) {
+	m.init()
+
+	err = m.execute(ctx, func(ctx context.Context) error {
+		ack, err := m.messageRoundtrip(ctx, command.asMessage(identity))
+
+		if err != nil {
+			return err
+		}
+
+		response = &CommandResponse{Ack: *ack}
+
+		if command.ExtendedResponse {
+			response.Extended, err = m.readExtendedResponse(ctx, identity, command.CommandBytes[0])
+		}
+
+		return err
+	})
+
+	return
+}
+
+// TrafficStatistics returns statistics about the observed powerline traffic.
+func (m *SerialPowerLineModem) TrafficStatistics() TrafficStatistics {
+	return m.shaper.Statistics()
+}
+
 // Monitor the Insteon network for changes for as long as the specified context remains valid.
 //
-// All events are pushed to the specified events channel.
+// All events are pushed to the specified events channel: the light state
+// changes and, as events of type MessageEventType, the other broadcasts and
+// unsolicited direct messages, like the reports of thermostats.
 func (m *SerialPowerLineModem) Monitor(ctx context.Context, events chan<- DeviceEvent) error {
 	m.init()
 
@@ -346,17 +544,30 @@ func (m *SerialPowerLineModem) Monitor(ctx context.Context, events chan<- Device
 	defer cancel()
 
 	for {
-		if msg, err := m.readMessage(ctx, cmdStandardMessageReceived, MessageFlagBroadcast); err == nil {
-			state := &LightState{}
-
-			if err := state.UnmarshalBinary(msg.CommandBytes[:]); err != nil {
+		if msg, err := m.readMessage(ctx, cmdStandardMessageReceived, 0); err == nil {
+			// Only broadcasts and unsolicited direct messages, like the
+			// reports of thermostats, are events: acknowledgments and
+			// all-link cleanups are not.
+			if msg.Flags&MessageFlagAck != 0 || msg.Flags&(MessageFlagBroadcast|MessageFlagAllLink) == MessageFlagAllLink {
 				continue
 			}
 
 			event := DeviceEvent{
 				Identity: msg.Source,
-				OnOff:    state.OnOff,
-				Change:   state.Change,
+				Message:  msg,
+			}
+
+			if msg.Flags&MessageFlagAllLink != 0 {
+				event.Group = msg.Target.AsGroup()
+			}
+
+			state := &LightState{}
+
+			if err := state.UnmarshalBinary(msg.CommandBytes[:]); err == nil {
+				event.OnOff = state.OnOff
+				event.Change = state.Change
+			} else {
+				event.Type = MessageEventType
 			}
 
 			select {
@@ -376,8 +587,17 @@ func (m *SerialPowerLineModem) Monitor(ctx context.Context, events chan<- Device
 
 func (m *SerialPowerLineModem) init() {
 	m.once.Do(func() {
+		// Leave enough time for a few attempts at the maximum hops count.
 		if m.ExecutionTimeout == 0 {
-			m.ExecutionTimeout = time.Second
+			m.ExecutionTimeout = time.Second * 5
+		}
+
+		if m.Hops == nil {
+			m.Hops = &HopsTracker{}
+		}
+
+		if m.MaxAttempts == 0 {
+			m.MaxAttempts = 3
 		}
 
 		m.ctx, m.cancel = context.WithCancel(context.Background())
@@ -405,17 +625,19 @@ func (m *SerialPowerLineModem) execute(ctx context.Context, fn func(context.Cont
 		cancel()
 	}()
 
+	m.closing.RLock()
+	defer m.closing.RUnlock()
+
+	if m.closed {
+		return ErrPowerLineModemClosed
+	}
+
 	// Wait until we can push the routine.
 	select {
 	case m.routines <- func() {
 		ctx, cancel := m.withInbox(ctx)
 		defer cancel()
 
-		// Set a default write delay of 10ms.
-		//
-		// This can be overriden by specific calls for a longer/shorter delay.
-		ctx = withWriteDelay(ctx, time.Millisecond*10)
-
 		//
 		ctx, subCancel := context.WithTimeout(ctx, m.ExecutionTimeout)
 		ch <- fn(ctx)
@@ -433,7 +655,66 @@ func (m *SerialPowerLineModem) execute(ctx context.Context, fn func(context.Cont
 }
 
 func (m *SerialPowerLineModem) readLoop(ctx context.Context) {
-	r := newPacketReader(m.Device)
+	for n := 1; ; n++ {
+		m.readPackets(ctx, m.getDevice())
+
+		if ctx.Err() != nil || m.Reopen == nil {
+			return
+		}
+
+		if !m.reconnect(ctx, n) {
+			return
+		}
+	}
+}
+
+// reconnect reopens the device, waiting longer after each failed attempt.
+func (m *SerialPowerLineModem) reconnect(ctx context.Context, n int) bool {
+	m.getDevice().Close()
+
+	for delay := minReconnectDelay; ; delay *= 2 {
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return false
+		}
+
+		device, err := m.Reopen()
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Reopening PowerLine Modem: %s.\n", err)
+			continue
+		}
+
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		// The PowerLine Modem was closed in the meantime.
+		if ctx.Err() != nil {
+			device.Close()
+			return false
+		}
+
+		m.Device = wrapPowerLineModemDevice(device, n)
+
+		return true
+	}
+}
+
+func (m *SerialPowerLineModem) getDevice() io.ReadWriteCloser {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.Device
+}
+
+// readPackets reads packets from the device until it fails.
+func (m *SerialPowerLineModem) readPackets(ctx context.Context, device io.ReadWriteCloser) {
+	r := newPacketReader(device)
 
 	for {
 		p, err := r.ReadPacket()
@@ -442,6 +723,8 @@ func (m *SerialPowerLineModem) readLoop(ctx context.Context) {
 			return
 		}
 
+		m.observe(p)
+
 		for _, ibx := range m.getInboxes() {
 			select {
 			case ibx.C <- p:
@@ -457,6 +740,28 @@ func (m *SerialPowerLineModem) readLoop(ctx context.Context) {
 	}
 }
 
+func (m *SerialPowerLineModem) observe(p *packet) {
+	switch p.CommandCode {
+	case cmdStandardMessageReceived, cmdExtendedMessageReceived:
+		msg := &Message{}
+
+		if err := msg.UnmarshalBinary(p.Payload); err == nil {
+			m.shaper.Observe(msg)
+		}
+	case cmdAllLinkCleanupStatusReport:
+		m.shaper.ObserveCleanupStatus()
+	}
+}
+
+const (
+	// minReconnectDelay is the time to wait before reopening a disconnected
+	// device.
+	minReconnectDelay = time.Millisecond * 100
+	// maxReconnectDelay is the maximum time to wait between two attempts to
+	// reopen a device.
+	maxReconnectDelay = time.Second * 30
+)
+
 const (
 	// messageStart is the marker at the beginning of commands.
 	messageStart byte = 0x02
@@ -481,7 +786,6 @@ type contextKey int
 
 const (
 	ctxInbox contextKey = iota
-	ctxWriteDelay
 )
 
 func (m *SerialPowerLineModem) withInbox(ctx context.Context) (context.Context, func()) {
@@ -500,18 +804,6 @@ func getInbox(ctx context.Context) *inbox {
 	return result
 }
 
-func withWriteDelay(ctx context.Context, writeDelay time.Duration) context.Context {
-	return context.WithValue(ctx, ctxWriteDelay, writeDelay)
-}
-
-func getWriteDelay(ctx context.Context) time.Duration {
-	if result := ctx.Value(ctxWriteDelay); result != nil {
-		return result.(time.Duration)
-	}
-
-	return 0
-}
-
 func (m *SerialPowerLineModem) acquireInbox(ctx context.Context) *inbox {
 	ibx := newInbox(ctx)
 
@@ -567,28 +859,73 @@ func (m *SerialPowerLineModem) readPacketTo(ctx context.Context, commandCode Com
 }
 
 func (m *SerialPowerLineModem) writePacket(ctx context.Context, p *packet) error {
-	// Make sure we wait enough since the last write.
-	now := time.Now().UTC()
-	delay := m.noWriteBefore.Sub(now)
-
-	select {
-	case <-time.After(delay):
-	case <-ctx.Done():
-		return ctx.Err()
+	// Give the modem time to process the previous command and hold back
+	// messages sent to the powerline while it is busy.
+	if err := m.shaper.Wait(ctx, isPowerLineCommandCode(p.CommandCode) && !m.DisableTrafficShaping); err != nil {
+		return err
 	}
 
-	w := newPacketWriter(m.Device)
+	w := newPacketWriter(m.getDevice())
 
 	err := w.WritePacket(p)
-
-	if writeDelay := getWriteDelay(ctx); writeDelay != 0 {
-		m.noWriteBefore = time.Now().UTC().Add(writeDelay)
-	}
+	m.shaper.ObserveWrite()
 
 	return err
 }
 
+// messageRoundtrip sends a message to a device and returns its
+// acknowledgment.
+//
+// The number of hops is chosen from what was learned about the device, and
+// increased on each new attempt.
 func (m *SerialPowerLineModem) messageRoundtrip(ctx context.Context, msg *Message) (*Message, error) {
+	hops := m.Hops.Hops(msg.Target)
+	attempts := m.MaxAttempts
+	var err error
+
+	// A relative command whose acknowledgment was lost was still applied, so
+	// it must not be sent again.
+	if isRelativeCommand(msg.CommandBytes) {
+		attempts = 1
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		msg.MaxHops = hops
+		msg.HopsLeft = hops
+
+		var ack *Message
+
+		if ack, err = m.messageAttempt(ctx, msg); err == nil {
+			m.Hops.Record(msg.Target, true, ack.HopsUsed())
+
+			if ack.IsNak() {
+				return nil, ErrDeviceNak{ID: msg.Target, Reason: NakReason(ack.CommandBytes[1])}
+			}
+
+			return ack, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		m.Hops.Record(msg.Target, false, 0)
+
+		if hops < maxHops {
+			hops++
+		}
+	}
+
+	return nil, err
+}
+
+// isRelativeCommand tells whether a command changes the state of a device
+// relatively to its current state, like the brighten and dim steps.
+func isRelativeCommand(commandBytes [2]byte) bool {
+	return commandBytes[0] == 0x15 || commandBytes[0] == 0x16
+}
+
+func (m *SerialPowerLineModem) messageAttempt(ctx context.Context, msg *Message) (*Message, error) {
 	payload, err := msg.MarshalBinary()
 
 	if err != nil {
@@ -602,19 +939,20 @@ func (m *SerialPowerLineModem) messageRoundtrip(ctx context.Context, msg *Messag
 
 	result := &Message{}
 
-	if msg.IsExtended() {
-		writeDelay := time.Second * time.Duration(26*msg.HopsLeft) / 60
-		ctx = withWriteDelay(ctx, writeDelay)
-	} else {
-		writeDelay := time.Second * time.Duration(12*msg.HopsLeft) / 60
-		ctx = withWriteDelay(ctx, writeDelay)
-	}
-
 	if err = m.roundtrip(ctx, p, result); err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	// Hold back the next writes until the message and its acknowledgment
+	// went through.
+	m.shaper.ObserveSent(msg)
+
+	// Give the acknowledgment some margin over its theoretical roundtrip.
+	timeout := messageRoundtripDuration(msg.IsExtended(), msg.MaxHops)*2 + time.Millisecond*200
+	ackCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return m.readAck(ackCtx, msg.Target)
 }
 
 func (m *SerialPowerLineModem) readMessage(ctx context.Context, commandCode CommandCode, flags MessageFlags) (*Message, error) {
@@ -631,8 +969,51 @@ func (m *SerialPowerLineModem) readMessage(ctx context.Context, commandCode Comm
 	}
 }
 
-func (m *SerialPowerLineModem) readStandardMessage(ctx context.Context) (*Message, error) {
-	return m.readMessage(ctx, cmdStandardMessageReceived, MessageFlagAck)
+// readAck reads the next acknowledgment (or NAK) sent by a device.
+func (m *SerialPowerLineModem) readAck(ctx context.Context, identity ID) (*Message, error) {
+	for {
+		msg, err := m.readMessage(ctx, cmdStandardMessageReceived, MessageFlagAck)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.Source == identity && msg.Flags&MessageFlagAllLink == 0 {
+			return msg, nil
+		}
+	}
+}
+
+// readExtendedResponse reads the next extended message sent directly by a
+// device for the specified command.
+func (m *SerialPowerLineModem) readExtendedResponse(ctx context.Context, identity ID, cmd1 byte) (*Message, error) {
+	for {
+		msg, err := m.readMessage(ctx, cmdExtendedMessageReceived, MessageFlagExtended)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.Source == identity && msg.Flags&(MessageFlagBroadcast|MessageFlagAllLink) == 0 && msg.CommandBytes[0] == cmd1 {
+			return msg, nil
+		}
+	}
+}
+
+// readSetButtonPressed reads the next "set button pressed" broadcast sent by
+// a device, which carries its category in place of the target.
+func (m *SerialPowerLineModem) readSetButtonPressed(ctx context.Context, identity ID) (*Message, error) {
+	for {
+		msg, err := m.readMessage(ctx, cmdStandardMessageReceived, MessageFlagBroadcast)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if msg.Source == identity && msg.Flags&MessageFlagAllLink == 0 && (msg.CommandBytes[0] == 0x01 || msg.CommandBytes[0] == 0x02) {
+			return msg, nil
+		}
+	}
 }
 
 func (m *SerialPowerLineModem) readExtendedMessage(ctx context.Context) (*Message, error) {
@@ -661,6 +1042,10 @@ func (m *SerialPowerLineModem) roundtrip(ctx context.Context, p *packet, result
 			break
 		}
 
+		if isPowerLineCommandCode(p.CommandCode) {
+			m.shaper.ObserveNak()
+		}
+
 		select {
 		case <-time.After(time.Millisecond * 150):
 		case <-ctx.Done():
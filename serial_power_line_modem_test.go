@@ -0,0 +1,159 @@
+package insteon
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePowerLineModemDevice answers like a PLM, with all the devices
+// acknowledging the standard messages sent to them.
+type fakePowerLineModemDevice struct {
+	responses chan []byte
+	closed    chan struct{}
+	once      sync.Once
+	pending   []byte
+}
+
+func newFakePowerLineModemDevice() *fakePowerLineModemDevice {
+	return &fakePowerLineModemDevice{
+		responses: make(chan []byte, 16),
+		closed:    make(chan struct{}),
+	}
+}
+
+func (d *fakePowerLineModemDevice) Read(buf []byte) (int, error) {
+	if len(d.pending) == 0 {
+		select {
+		case d.pending = <-d.responses:
+		case <-d.closed:
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(buf, d.pending)
+	d.pending = d.pending[n:]
+
+	return n, nil
+}
+
+func (d *fakePowerLineModemDevice) Write(buf []byte) (int, error) {
+	select {
+	case <-d.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	if len(buf) == 8 && buf[0] == messageStart && CommandCode(buf[1]) == cmdSendStandardOrExtendedMessage {
+		d.responses <- append(append([]byte{}, buf...), messageAck)
+		d.responses <- []byte{
+			messageStart, byte(cmdStandardMessageReceived),
+			buf[2], buf[3], buf[4],
+			0x11, 0x22, 0x33,
+			byte(MessageFlagAck) | 0x0f,
+			buf[6], buf[7],
+		}
+	}
+
+	return len(buf), nil
+}
+
+func (d *fakePowerLineModemDevice) Close() error {
+	d.once.Do(func() { close(d.closed) })
+
+	return nil
+}
+
+func TestSerialPowerLineModemFaults(t *testing.T) {
+	testCases := []struct {
+		name     string
+		scenario FaultScenario
+		// failures tells whether some requests are expected to fail before
+		// the PowerLine Modem recovers.
+		failures bool
+		// reopens tells whether the device is expected to be reopened.
+		reopens bool
+	}{
+		{
+			name:     "nak-retry",
+			scenario: FaultScenario{Seed: 1, NakRate: 0.3},
+		},
+		{
+			name:     "resync-after-corruption",
+			scenario: FaultScenario{Seed: 2, CorruptRate: 0.3},
+			failures: true,
+		},
+		{
+			name:     "recovery-after-disconnect",
+			scenario: FaultScenario{Seed: 3, DisconnectRate: 0.3},
+			failures: true,
+			reopens:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var lock sync.Mutex
+			opens := 0
+
+			open := func() (io.ReadWriteCloser, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				scenario := testCase.scenario
+				scenario.Seed += int64(opens)
+				opens++
+
+				return &faultInjectionReadWriteCloser{
+					ReadWriteCloser: newFakePowerLineModemDevice(),
+					Scenario:        scenario,
+				}, nil
+			}
+
+			device, _ := open()
+			m := &SerialPowerLineModem{
+				Device:           device,
+				Reopen:           open,
+				ExecutionTimeout: time.Second,
+				MaxAttempts:      1,
+			}
+			defer m.Close()
+
+			failures := 0
+
+			for i := 0; i < 6; i++ {
+				for attempt := 0; ; attempt++ {
+					if attempt == 10 {
+						t.Fatalf("request %d never succeeded", i)
+					}
+
+					ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+					err := m.Beep(ctx, ID{0xaa, 0xbb, 0xcc})
+					cancel()
+
+					if err == nil {
+						break
+					}
+
+					failures++
+
+					// Give the PowerLine Modem time to reopen the device.
+					time.Sleep(time.Millisecond * 100)
+				}
+			}
+
+			if testCase.failures != (failures > 0) {
+				t.Errorf("expected failures to be %t but got %d", testCase.failures, failures)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if testCase.reopens != (opens > 1) {
+				t.Errorf("expected reopens to be %t but the device was opened %d time(s)", testCase.reopens, opens)
+			}
+		})
+	}
+}
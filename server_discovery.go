@@ -0,0 +1,144 @@
+package insteon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServerConfiguration contains the configuration of the local server.
+type ServerConfiguration struct {
+	// URL is the URL of a server to send commands to.
+	URL string `yaml:"url"`
+}
+
+// ServerInfo describes a running server.
+type ServerInfo struct {
+	URL         string `json:"url"`
+	PID         int    `json:"pid"`
+	CommandLine string `json:"command_line"`
+}
+
+// RegisterServer records a running server in the server lock file, so that
+// other processes can find it.
+//
+// The returned function removes the registration.
+func RegisterServer(serverURL string) (func(), error) {
+	path := getServerLockPath()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("creating server lock directory: %s", err)
+	}
+
+	data, err := json.Marshal(ServerInfo{
+		URL:         serverURL,
+		PID:         os.Getpid(),
+		CommandLine: strings.Join(os.Args, " "),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("writing server lock file: %s", err)
+	}
+
+	return func() {
+		if info, err := readServerLockFile(path); err == nil && info.PID == os.Getpid() {
+			os.Remove(path)
+		}
+	}, nil
+}
+
+// FindServer returns the URL of a running server, if any.
+//
+// The server URL from the configuration takes precedence over the one in
+// the server lock file. A server is only returned if it answers as an ion
+// server, with access to its PowerLine Modem.
+func FindServer(config *Configuration) (string, bool) {
+	if config != nil && config.Server.URL != "" {
+		return config.Server.URL, isServerAvailable(config.Server.URL)
+	}
+
+	info, err := readServerLockFile(getServerLockPath())
+
+	if err != nil {
+		return "", false
+	}
+
+	return info.URL, isServerAvailable(info.URL)
+}
+
+// ServerURLFromEndpoint returns the URL of a server listening on the
+// specified endpoint.
+func ServerURLFromEndpoint(endpoint string) string {
+	host, port, err := net.SplitHostPort(endpoint)
+
+	if err != nil {
+		return "http://" + endpoint
+	}
+
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// isServerAvailable tells whether an ion server answers at an URL, rather
+// than just anything listening on its port, like after the server crashed.
+func isServerAvailable(serverURL string) bool {
+	u, err := url.Parse(serverURL)
+
+	if err != nil {
+		return false
+	}
+
+	u.Path = "/plm/im-info"
+	client := &http.Client{Timeout: time.Second * 2}
+	resp, err := client.Get(u.String())
+
+	if err != nil {
+		return false
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return false
+	}
+
+	return json.NewDecoder(resp.Body).Decode(&IMInfo{}) == nil
+}
+
+func readServerLockFile(path string) (*ServerInfo, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ServerInfo{}
+
+	if err = json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func getServerLockPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "ion", "server.json")
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("ion-%d", os.Getuid()), "server.json")
+}
@@ -0,0 +1,690 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	commandBytesGetTemperature    = [2]byte{0x6a, 0x00}
+	commandBytesGetHumidity       = [2]byte{0x6a, 0x60}
+	commandBytesGetThermostatData = [2]byte{0x2e, 0x02}
+)
+
+// The first command bytes of thermostat commands and reports.
+const (
+	cmd1ThermostatControl  byte = 0x6b
+	cmd1SetCoolSetpoint    byte = 0x6c
+	cmd1SetHeatSetpoint    byte = 0x6d
+	cmd1TemperatureReport  byte = 0x6e
+	cmd1HumidityReport     byte = 0x6f
+	cmd1ModeReport         byte = 0x70
+	cmd1CoolSetpointReport byte = 0x71
+	cmd1HeatSetpointReport byte = 0x72
+)
+
+// thermostatStatusCelsius is the flag of the thermostat status that tells
+// its temperatures are in degrees Celsius rather than Fahrenheit.
+const thermostatStatusCelsius byte = 0x08
+
+// thermostatDataSetClock is the data set code of the clock in the extended
+// set command of thermostats.
+const thermostatDataSetClock byte = 0x02
+
+// The second command bytes of the thermostat control command.
+var (
+	thermostatControlModes = map[ThermostatMode]byte{
+		ThermostatHeat:    0x04,
+		ThermostatCool:    0x05,
+		ThermostatAuto:    0x06,
+		ThermostatOff:     0x09,
+		ThermostatProgram: 0x0c,
+	}
+	thermostatControlFanModes = map[ThermostatFanMode]byte{
+		ThermostatFanOn:   0x07,
+		ThermostatFanAuto: 0x08,
+	}
+)
+
+// Thermostat is a device that controls heating and cooling.
+//
+// Thermostats report changes with direct messages, which Devices.Monitor
+// decodes into events of types temperature, humidity, mode, cool_setpoint
+// and heat_setpoint.
+type Thermostat interface {
+	Device
+	GetTemperature(ctx context.Context) (float64, error)
+	GetHumidity(ctx context.Context) (float64, error)
+	GetStatus(ctx context.Context) (*ThermostatState, error)
+	SetMode(ctx context.Context, mode ThermostatMode) error
+	SetFanMode(ctx context.Context, fanMode ThermostatFanMode) error
+	SetCoolSetpoint(ctx context.Context, temperature float64) error
+	SetHeatSetpoint(ctx context.Context, temperature float64) error
+	SyncClock(ctx context.Context, now time.Time) error
+}
+
+// ThermostatMode is the system mode of a thermostat.
+type ThermostatMode int
+
+const (
+	// ThermostatOff indicates a thermostat that neither heats nor cools.
+	ThermostatOff ThermostatMode = iota
+	// ThermostatAuto indicates a thermostat that heats or cools as needed.
+	ThermostatAuto
+	// ThermostatHeat indicates a heating thermostat.
+	ThermostatHeat
+	// ThermostatCool indicates a cooling thermostat.
+	ThermostatCool
+	// ThermostatProgram indicates a thermostat that follows its program.
+	ThermostatProgram
+)
+
+var thermostatModes = []string{"off", "auto", "heat", "cool", "program"}
+
+func (m ThermostatMode) String() string {
+	if int(m) < len(thermostatModes) {
+		return thermostatModes[m]
+	}
+
+	return fmt.Sprintf("unknown mode %02x", int(m))
+}
+
+// UnmarshalText -
+func (m *ThermostatMode) UnmarshalText(b []byte) error {
+	for i, mode := range thermostatModes {
+		if mode == string(b) {
+			*m = ThermostatMode(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported thermostat mode: %s", string(b))
+}
+
+// MarshalText -
+func (m ThermostatMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// ThermostatFanMode is the fan mode of a thermostat.
+type ThermostatFanMode int
+
+const (
+	// ThermostatFanAuto indicates a fan that only runs when needed.
+	ThermostatFanAuto ThermostatFanMode = iota
+	// ThermostatFanOn indicates a fan that always runs.
+	ThermostatFanOn
+)
+
+func (m ThermostatFanMode) String() string {
+	if m == ThermostatFanOn {
+		return "on"
+	}
+
+	return "auto"
+}
+
+// UnmarshalText -
+func (m *ThermostatFanMode) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "auto":
+		*m = ThermostatFanAuto
+	case "on":
+		*m = ThermostatFanOn
+	default:
+		return fmt.Errorf("unsupported thermostat fan mode: %s", string(b))
+	}
+
+	return nil
+}
+
+// MarshalText -
+func (m ThermostatFanMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// ThermostatState is the state of a thermostat.
+//
+// Temperatures are in degrees Celsius and the humidity in the [0, 1] range.
+type ThermostatState struct {
+	Temperature  float64           `json:"temperature"`
+	Humidity     float64           `json:"humidity"`
+	Mode         ThermostatMode    `json:"mode"`
+	FanMode      ThermostatFanMode `json:"fan_mode"`
+	CoolSetpoint float64           `json:"cool_setpoint"`
+	HeatSetpoint float64           `json:"heat_setpoint"`
+}
+
+// ThermostatSettings are the settings of a thermostat to change.
+//
+// Only the specified fields are changed. Setpoints are in degrees Celsius.
+type ThermostatSettings struct {
+	Mode         *ThermostatMode    `json:"mode,omitempty"`
+	FanMode      *ThermostatFanMode `json:"fan_mode,omitempty"`
+	CoolSetpoint *float64           `json:"cool_setpoint,omitempty"`
+	HeatSetpoint *float64           `json:"heat_setpoint,omitempty"`
+}
+
+// thermostatData is the data set of a thermostat, as returned by the
+// extended status request.
+//
+// D1 to D4 are the day of the week, hours, minutes and seconds of its
+// clock, D5 the system mode in its low nibble and the fan mode in its high
+// nibble, D6 the cool setpoint, D7 the humidity, D8 and D9 the temperature in
+// tenths of degrees, D10 the status flags and D11 the heat setpoint.
+//
+// Setpoints are in half degrees and all temperatures are in the unit of the
+// thermostat, as given by its status flags.
+type thermostatData [14]byte
+
+func (b thermostatData) celsius() bool {
+	return b[9]&thermostatStatusCelsius != 0
+}
+
+func (b thermostatData) state() *ThermostatState {
+	mode, fanMode := byteToThermostatModes(b[4])
+	celsius := b.celsius()
+
+	return &ThermostatState{
+		Temperature:  toCelsius(float64(int16(b[7])<<8|int16(b[8]))/10, celsius),
+		Humidity:     float64(b[6]) / 100,
+		Mode:         mode,
+		FanMode:      fanMode,
+		CoolSetpoint: byteToTemperature(b[5], celsius),
+		HeatSetpoint: byteToTemperature(b[10], celsius),
+	}
+}
+
+// The units of the temperatures in the attributes of thermostat events.
+const (
+	temperatureUnitCelsius = "celsius"
+	temperatureUnitUnknown = "unknown"
+)
+
+// setThermostatUnit records the unit of a thermostat, true for degrees
+// Celsius.
+func (d *Devices) setThermostatUnit(identity ID, celsius bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.thermostatUnits[identity] = celsius
+}
+
+// getThermostatUnit returns the unit of a thermostat, if its status was read.
+func (d *Devices) getThermostatUnit(identity ID) (celsius bool, ok bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	celsius, ok = d.thermostatUnits[identity]
+
+	return
+}
+
+// convertTemperatures converts the temperature that an event reports in the
+// unit of its thermostat to degrees Celsius, if that unit is known.
+//
+// Reports don't tell their unit: until the status of the thermostat is read,
+// the value remains in degrees of its unit, which is marked unknown.
+func (d *Devices) convertTemperatures(event *DeviceEvent) {
+	if event.Attributes["unit"] != temperatureUnitUnknown {
+		return
+	}
+
+	celsius, ok := d.getThermostatUnit(event.Identity)
+
+	if !ok {
+		return
+	}
+
+	if value, ok := event.Attributes["value"].(float64); ok {
+		event.Attributes["value"] = toCelsius(value, celsius)
+		event.Attributes["unit"] = temperatureUnitCelsius
+	}
+}
+
+func byteToThermostatModes(b byte) (ThermostatMode, ThermostatFanMode) {
+	fanMode := ThermostatFanAuto
+
+	if b>>4 != 0 {
+		fanMode = ThermostatFanOn
+	}
+
+	return ThermostatMode(b & 0x0f), fanMode
+}
+
+type thermostat struct {
+	*BaseDevice
+}
+
+// GetTemperature returns the ambient temperature, in degrees Celsius.
+func (d *thermostat) GetTemperature(ctx context.Context) (float64, error) {
+	value, err := d.getZoneInformation(ctx, "temperature", commandBytesGetTemperature)
+
+	if err != nil {
+		return 0, err
+	}
+
+	celsius, err := d.celsius(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// The temperature is reported in half degrees.
+	return byteToTemperature(value, celsius), nil
+}
+
+// GetHumidity returns the relative humidity, in the [0, 1] range.
+func (d *thermostat) GetHumidity(ctx context.Context) (float64, error) {
+	value, err := d.getZoneInformation(ctx, "humidity", commandBytesGetHumidity)
+
+	return float64(value) / 100, err
+}
+
+// GetStatus returns the status of the thermostat, read through an extended
+// status request.
+func (d *thermostat) GetStatus(ctx context.Context) (*ThermostatState, error) {
+	value, err := d.Cached(ctx, "status", func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		command := NewExtendedCommand(commandBytesGetThermostatData, [14]byte{})
+		command.ExtendedResponse = true
+
+		response, err := plm.SendCommand(ctx, d.id, command)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if response.Extended == nil {
+			return nil, fmt.Errorf("no status received from thermostat %s", d.id)
+		}
+
+		data := thermostatData(response.Extended.UserData)
+		d.devices.setThermostatUnit(d.id, data.celsius())
+
+		return data.state(), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*ThermostatState), nil
+}
+
+// SetMode sets the system mode of the thermostat.
+func (d *thermostat) SetMode(ctx context.Context, mode ThermostatMode) error {
+	cmd2, ok := thermostatControlModes[mode]
+
+	if !ok {
+		return fmt.Errorf("unsupported thermostat mode: %s", mode)
+	}
+
+	_, err := d.SendCommand(ctx, NewCommand([2]byte{cmd1ThermostatControl, cmd2}))
+
+	return err
+}
+
+// SetFanMode sets the fan mode of the thermostat.
+func (d *thermostat) SetFanMode(ctx context.Context, fanMode ThermostatFanMode) error {
+	cmd2, ok := thermostatControlFanModes[fanMode]
+
+	if !ok {
+		return fmt.Errorf("unsupported thermostat fan mode: %s", fanMode)
+	}
+
+	_, err := d.SendCommand(ctx, NewCommand([2]byte{cmd1ThermostatControl, cmd2}))
+
+	return err
+}
+
+// SetCoolSetpoint sets the cool setpoint of the thermostat, in degrees
+// Celsius.
+func (d *thermostat) SetCoolSetpoint(ctx context.Context, temperature float64) error {
+	celsius, err := d.celsius(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = d.SendCommand(ctx, NewCommand([2]byte{cmd1SetCoolSetpoint, temperatureToByte(temperature, celsius)}))
+
+	return err
+}
+
+// SetHeatSetpoint sets the heat setpoint of the thermostat, in degrees
+// Celsius.
+func (d *thermostat) SetHeatSetpoint(ctx context.Context, temperature float64) error {
+	celsius, err := d.celsius(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = d.SendCommand(ctx, NewCommand([2]byte{cmd1SetHeatSetpoint, temperatureToByte(temperature, celsius)}))
+
+	return err
+}
+
+// SyncClock sets the clock of the thermostat.
+func (d *thermostat) SyncClock(ctx context.Context, now time.Time) error {
+	userData := [14]byte{
+		thermostatDataSetClock,
+		byte(now.Weekday()),
+		byte(now.Hour()),
+		byte(now.Minute()),
+		byte(now.Second()),
+	}
+
+	_, err := d.SendCommand(ctx, NewExtendedCommand(commandBytesGetThermostatData, userData))
+
+	return err
+}
+
+// getZoneInformation returns the zone information that the thermostat
+// reports in its acknowledgment.
+func (d *thermostat) getZoneInformation(ctx context.Context, name string, commandBytes [2]byte) (byte, error) {
+	value, err := d.Cached(ctx, name, func(ctx context.Context, plm PowerLineModem) (interface{}, error) {
+		response, err := plm.SendCommand(ctx, d.id, NewCommand(commandBytes))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return response.Ack.CommandBytes[1], nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return value.(byte), nil
+}
+
+// celsius returns whether the thermostat uses degrees Celsius, reading its
+// status if its unit is not known yet.
+func (d *thermostat) celsius(ctx context.Context) (bool, error) {
+	if celsius, ok := d.devices.getThermostatUnit(d.id); ok {
+		return celsius, nil
+	}
+
+	if _, err := d.GetStatus(ctx); err != nil {
+		return false, fmt.Errorf("reading thermostat unit: %s", err)
+	}
+
+	celsius, _ := d.devices.getThermostatUnit(d.id)
+
+	return celsius, nil
+}
+
+// toCelsius converts a temperature in the unit of a thermostat to degrees
+// Celsius, rounded to the tenth.
+func toCelsius(temperature float64, celsius bool) float64 {
+	if !celsius {
+		temperature = (temperature - 32) * 5 / 9
+	}
+
+	return math.Round(temperature*10) / 10
+}
+
+// fromCelsius converts a temperature in degrees Celsius to the unit of a
+// thermostat.
+func fromCelsius(temperature float64, celsius bool) float64 {
+	if !celsius {
+		temperature = temperature*9/5 + 32
+	}
+
+	return temperature
+}
+
+// byteToTemperature returns a temperature in degrees Celsius from half
+// degrees in the unit of a thermostat, as thermostats report it.
+func byteToTemperature(value byte, celsius bool) float64 {
+	return toCelsius(float64(value)/2, celsius)
+}
+
+// temperatureToByte returns a temperature in half degrees in the unit of a
+// thermostat, as thermostats expect it.
+func temperatureToByte(temperature float64, celsius bool) byte {
+	value := math.Round(fromCelsius(temperature, celsius) * 2)
+
+	if value < 0 {
+		return 0
+	}
+
+	if value > 0xff {
+		return 0xff
+	}
+
+	return byte(value)
+}
+
+// thermostatDriver is the driver of thermostats.
+type thermostatDriver struct {
+	BaseDeviceDriver
+}
+
+func (thermostatDriver) Name() string {
+	return "thermostat"
+}
+
+func (thermostatDriver) NewDevice(base *BaseDevice) Device {
+	return &thermostat{base}
+}
+
+// DecodeEvent decodes the reports that thermostats send directly when their
+// temperature, humidity, mode or setpoints change.
+//
+// Temperatures are left in the unknown unit of the thermostat, for Devices
+// to convert them.
+func (d thermostatDriver) DecodeEvent(msg *Message, event *DeviceEvent) bool {
+	value := msg.CommandBytes[1]
+	temperature := map[string]interface{}{"value": float64(value) / 2, "unit": temperatureUnitUnknown}
+
+	switch msg.CommandBytes[0] {
+	case cmd1TemperatureReport:
+		event.Type = "temperature"
+		event.Attributes = temperature
+	case cmd1HumidityReport:
+		event.Type = "humidity"
+		event.Attributes = map[string]interface{}{"value": float64(value) / 100}
+	case cmd1ModeReport:
+		mode, fanMode := byteToThermostatModes(value)
+		event.Type = "mode"
+		event.Attributes = map[string]interface{}{"mode": mode.String(), "fan_mode": fanMode.String()}
+	case cmd1CoolSetpointReport:
+		event.Type = "cool_setpoint"
+		event.Attributes = temperature
+	case cmd1HeatSetpointReport:
+		event.Type = "heat_setpoint"
+		event.Attributes = temperature
+	default:
+		return d.BaseDeviceDriver.DecodeEvent(msg, event)
+	}
+
+	return true
+}
+
+// NewState returns the settings to change, as the rest of the state of
+// thermostats is read-only.
+func (thermostatDriver) NewState() interface{} {
+	return &ThermostatSettings{}
+}
+
+func (thermostatDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	thermostat, ok := device.(Thermostat)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return thermostat.GetStatus(ctx)
+}
+
+// SetState changes the specified settings of a thermostat.
+func (thermostatDriver) SetState(ctx context.Context, device Device, state interface{}) error {
+	thermostat, ok := device.(Thermostat)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	settings, ok := state.(*ThermostatSettings)
+
+	if !ok {
+		return fmt.Errorf("unexpected state type: %T", state)
+	}
+
+	if settings.Mode != nil {
+		if err := thermostat.SetMode(ctx, *settings.Mode); err != nil {
+			return err
+		}
+	}
+
+	if settings.FanMode != nil {
+		if err := thermostat.SetFanMode(ctx, *settings.FanMode); err != nil {
+			return err
+		}
+	}
+
+	if settings.CoolSetpoint != nil {
+		if err := thermostat.SetCoolSetpoint(ctx, *settings.CoolSetpoint); err != nil {
+			return err
+		}
+	}
+
+	if settings.HeatSetpoint != nil {
+		if err := thermostat.SetHeatSetpoint(ctx, *settings.HeatSetpoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (thermostatDriver) Commands() []DeviceCommand {
+	return []DeviceCommand{
+		{
+			Name:        "mode",
+			Usage:       "<off|auto|heat|cool|program>",
+			Description: "Set the system mode",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				thermostat, err := asThermostat(device, args, 1)
+
+				if err != nil {
+					return nil, err
+				}
+
+				var mode ThermostatMode
+
+				if err := mode.UnmarshalText([]byte(args[0])); err != nil {
+					return nil, err
+				}
+
+				return nil, thermostat.SetMode(ctx, mode)
+			},
+		},
+		{
+			Name:        "fan",
+			Usage:       "<auto|on>",
+			Description: "Set the fan mode",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				thermostat, err := asThermostat(device, args, 1)
+
+				if err != nil {
+					return nil, err
+				}
+
+				var fanMode ThermostatFanMode
+
+				if err := fanMode.UnmarshalText([]byte(args[0])); err != nil {
+					return nil, err
+				}
+
+				return nil, thermostat.SetFanMode(ctx, fanMode)
+			},
+		},
+		{
+			Name:        "cool",
+			Usage:       "<temperature>",
+			Description: "Set the cool setpoint, in degrees Celsius",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				thermostat, err := asThermostat(device, args, 1)
+
+				if err != nil {
+					return nil, err
+				}
+
+				temperature, err := strconv.ParseFloat(args[0], 64)
+
+				if err != nil {
+					return nil, fmt.Errorf("parsing temperature: %s", err)
+				}
+
+				return nil, thermostat.SetCoolSetpoint(ctx, temperature)
+			},
+		},
+		{
+			Name:        "heat",
+			Usage:       "<temperature>",
+			Description: "Set the heat setpoint, in degrees Celsius",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				thermostat, err := asThermostat(device, args, 1)
+
+				if err != nil {
+					return nil, err
+				}
+
+				temperature, err := strconv.ParseFloat(args[0], 64)
+
+				if err != nil {
+					return nil, fmt.Errorf("parsing temperature: %s", err)
+				}
+
+				return nil, thermostat.SetHeatSetpoint(ctx, temperature)
+			},
+		},
+		{
+			Name:        "sync-clock",
+			Description: "Set the clock to the current time",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				thermostat, err := asThermostat(device, args, 0)
+
+				if err != nil {
+					return nil, err
+				}
+
+				return nil, thermostat.SyncClock(ctx, time.Now())
+			},
+		},
+		{
+			Name:        "beep",
+			Description: "Make the device beep",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				return nil, device.Beep(ctx)
+			},
+		},
+	}
+}
+
+// asThermostat returns a device as a thermostat, after checking the number
+// of arguments of a command.
+func asThermostat(device Device, args []string, count int) (Thermostat, error) {
+	thermostat, ok := device.(Thermostat)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if len(args) != count {
+		return nil, fmt.Errorf("expected %d argument(s) but got %d", count, len(args))
+	}
+
+	return thermostat, nil
+}
+
+func init() {
+	RegisterMainCategoryDeviceDriver(thermostatDriver{}, climateControlHeating)
+}
@@ -0,0 +1,305 @@
+package insteon
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"time"
+)
+
+const (
+	// pskChallengeSize is the size of the pre-shared key handshake
+	// challenge.
+	pskChallengeSize = 32
+	// pskHandshakeTimeout is the time given to a pre-shared key handshake.
+	pskHandshakeTimeout = time.Second * 5
+	// pskExporterLabel is the label used to bind the pre-shared key
+	// handshake to the TLS session.
+	pskExporterLabel = "EXPORTER-ion-psk"
+)
+
+// ErrPreSharedKeyMismatch is returned when a pre-shared key handshake fails.
+var ErrPreSharedKeyMismatch = errors.New("pre-shared key mismatch")
+
+// NewTLSPowerLineModem instantiates a new remote PowerLine Modem over TLS.
+//
+// The URL must be of the form tls://host:port and supports the following
+// query parameters:
+//
+// - cert and key: the client certificate and private key files.
+// - ca: the file of the CA certificates to verify the server with.
+// - psk: the file of a pre-shared key to authenticate with.
+func NewTLSPowerLineModem(u *url.URL) (*SerialPowerLineModem, error) {
+	query := u.Query()
+	config := &tls.Config{
+		ServerName: u.Hostname(),
+	}
+
+	if certFile := query.Get("cert"); certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, query.Get("key"))
+
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %s", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile := query.Get("ca"); caFile != "" {
+		pool, err := loadCertPool(caFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	dial := func() (io.ReadWriteCloser, error) {
+		conn, err := tls.Dial("tcp", u.Host, config)
+
+		if err != nil {
+			return nil, fmt.Errorf("opening remote serial port: %s", err)
+		}
+
+		if err = pskAuthenticate(conn, query); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	}
+
+	device, err := dial()
+
+	if err != nil {
+		return nil, err
+	}
+
+	m := newSerialPowerLineModem(device)
+	m.Reopen = dial
+
+	return m, nil
+}
+
+// dialPowerLineModem opens a PowerLine Modem shared through a tcp:// or
+// unix:// URL.
+//
+// The URL supports the psk query parameter: the file of a pre-shared key to
+// authenticate with.
+func dialPowerLineModem(u *url.URL) (*SerialPowerLineModem, error) {
+	address := u.Host
+
+	if u.Scheme == "unix" {
+		address = u.Path
+	}
+
+	dial := func() (io.ReadWriteCloser, error) {
+		conn, err := net.Dial(u.Scheme, address)
+
+		if err != nil {
+			return nil, fmt.Errorf("opening remote serial port: %s", err)
+		}
+
+		if err = pskAuthenticate(conn, u.Query()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	}
+
+	device, err := dial()
+
+	if err != nil {
+		return nil, err
+	}
+
+	m := newSerialPowerLineModem(device)
+	m.Reopen = dial
+
+	return m, nil
+}
+
+// NewServerTLSConfig returns a TLS configuration for a PowerLine Modem
+// server.
+//
+// If a client CA file is specified, clients must present a certificate
+// signed by one of its CAs.
+func NewServerTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("loading server certificate: %s", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// LoadPreSharedKey loads a pre-shared key from a file.
+func LoadPreSharedKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("reading pre-shared key: %s", err)
+	}
+
+	psk := bytes.TrimSpace(data)
+
+	if len(psk) == 0 {
+		return nil, fmt.Errorf("empty pre-shared key in %s", path)
+	}
+
+	return psk, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("reading CA certificates: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no CA certificate found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// pskAuthenticate authenticates to a server with the pre-shared key file of
+// the psk query parameter, if there is one.
+func pskAuthenticate(conn net.Conn, query url.Values) error {
+	pskFile := query.Get("psk")
+
+	if pskFile == "" {
+		return nil
+	}
+
+	psk, err := LoadPreSharedKey(pskFile)
+
+	if err != nil {
+		return err
+	}
+
+	if err = pskClientHandshake(conn, psk); err != nil {
+		return fmt.Errorf("authenticating to remote serial port: %s", err)
+	}
+
+	return nil
+}
+
+// pskClientHandshake proves to the server that we know the pre-shared key.
+//
+// The server sends a random challenge, to which the client answers with an
+// HMAC of the challenge, bound to the TLS session when there is one. The
+// server then acknowledges.
+func pskClientHandshake(conn net.Conn, psk []byte) error {
+	conn.SetDeadline(time.Now().Add(pskHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	challenge := make([]byte, pskChallengeSize)
+
+	if _, err := io.ReadFull(conn, challenge); err != nil {
+		return fmt.Errorf("reading challenge: %s", err)
+	}
+
+	response, err := pskResponse(conn, psk, challenge)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = conn.Write(response); err != nil {
+		return fmt.Errorf("writing response: %s", err)
+	}
+
+	ack := make([]byte, 1)
+
+	if _, err = io.ReadFull(conn, ack); err != nil || ack[0] != messageAck {
+		return ErrPreSharedKeyMismatch
+	}
+
+	return nil
+}
+
+// pskServerHandshake verifies that the client knows the pre-shared key.
+func pskServerHandshake(conn net.Conn, psk []byte) error {
+	conn.SetDeadline(time.Now().Add(pskHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	challenge := make([]byte, pskChallengeSize)
+
+	if _, err := rand.Read(challenge); err != nil {
+		return fmt.Errorf("generating challenge: %s", err)
+	}
+
+	if _, err := conn.Write(challenge); err != nil {
+		return fmt.Errorf("writing challenge: %s", err)
+	}
+
+	response := make([]byte, sha256.Size)
+
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return fmt.Errorf("reading response: %s", err)
+	}
+
+	expected, err := pskResponse(conn, psk, challenge)
+
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(response, expected) {
+		return ErrPreSharedKeyMismatch
+	}
+
+	_, err = conn.Write([]byte{messageAck})
+
+	return err
+}
+
+func pskResponse(conn net.Conn, psk []byte, challenge []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, psk)
+	mac.Write(challenge)
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		keyingMaterial, err := state.ExportKeyingMaterial(pskExporterLabel, nil, 32)
+
+		if err != nil {
+			return nil, fmt.Errorf("exporting keying material: %s", err)
+		}
+
+		mac.Write(keyingMaterial)
+	}
+
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,218 @@
+package insteon
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Insteon powerline timing, as described in the Insteon whitepaper.
+//
+// Messages are synchronized on the powerline zero crossings (120 per second
+// at 60Hz). A standard message takes 6 zero crossings and an extended
+// message takes 13. Every hop retransmits the whole message.
+const (
+	zeroCrossingPeriod       = time.Second / 120
+	standardMessageCrossings = 6
+	extendedMessageCrossings = 13
+
+	// cleanupPeriod is the time we expect an all-link cleanup sequence to
+	// last after a group broadcast, unless we observe it ending sooner.
+	cleanupPeriod = time.Second
+
+	// modemWriteDelay is the time the modem needs after a command before it
+	// accepts the next one.
+	modemWriteDelay = time.Millisecond * 10
+)
+
+// messageDuration returns the time it takes to send a message over the
+// specified number of hops.
+func messageDuration(extended bool, hops int) time.Duration {
+	crossings := standardMessageCrossings
+
+	if extended {
+		crossings = extendedMessageCrossings
+	}
+
+	return zeroCrossingPeriod * time.Duration(crossings*(hops+1))
+}
+
+// messageRoundtripDuration returns the time it takes to send a message and
+// to get its acknowledgment back.
+func messageRoundtripDuration(extended bool, hops int) time.Duration {
+	return messageDuration(extended, hops) + messageDuration(false, hops)
+}
+
+// TrafficStatistics contains statistics about the powerline traffic.
+type TrafficStatistics struct {
+	MessagesSent     uint64        `json:"messages_sent"`
+	MessagesReceived uint64        `json:"messages_received"`
+	ModemNaks        uint64        `json:"modem_naks"`
+	DeviceAcks       uint64        `json:"device_acks"`
+	DeviceNaks       uint64        `json:"device_naks"`
+	Broadcasts       uint64        `json:"broadcasts"`
+	Cleanups         uint64        `json:"cleanups"`
+	DelayedWrites    uint64        `json:"delayed_writes"`
+	TotalDelay       time.Duration `json:"total_delay"`
+}
+
+// trafficShaper tracks the powerline traffic to determine when it is safe to
+// send new messages.
+type trafficShaper struct {
+	lock         sync.Mutex
+	busyUntil    time.Time
+	cleanupUntil time.Time
+	writeUntil   time.Time
+	stats        TrafficStatistics
+}
+
+// Observe a message received from the powerline.
+func (s *trafficShaper) Observe(msg *Message) {
+	now := time.Now().UTC()
+	extended := msg.IsExtended()
+
+	// The message will be repeated for as many hops as it has left.
+	busy := messageDuration(extended, msg.HopsLeft-1)
+
+	if msg.HopsLeft == 0 {
+		busy = 0
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stats.MessagesReceived++
+
+	switch {
+	case msg.Flags&(MessageFlagBroadcast|MessageFlagAllLink) == MessageFlagBroadcast|MessageFlagAllLink:
+		// A group broadcast is followed by a cleanup sequence with every
+		// linked responder.
+		s.stats.Broadcasts++
+		s.extendCleanup(now.Add(busy + cleanupPeriod))
+	case msg.IsNak():
+		s.stats.DeviceNaks++
+	case msg.Flags&MessageFlagAck == MessageFlagAck:
+		s.stats.DeviceAcks++
+	case msg.Flags&MessageFlagBroadcast == MessageFlagBroadcast:
+		s.stats.Broadcasts++
+	case msg.Flags&MessageFlagAllLink == MessageFlagAllLink:
+		// A cleanup message: its target will acknowledge it.
+		s.stats.Cleanups++
+		busy += messageDuration(false, msg.MaxHops)
+		s.extendCleanup(now.Add(busy + messageRoundtripDuration(false, msg.MaxHops)))
+	default:
+		// A direct message: its target will acknowledge it.
+		busy += messageDuration(false, msg.MaxHops)
+	}
+
+	s.extendBusy(now.Add(busy))
+}
+
+// ObserveCleanupStatus is called when the modem reports the end of its own
+// cleanup sequence.
+func (s *trafficShaper) ObserveCleanupStatus() {
+	s.lock.Lock()
+	s.cleanupUntil = time.Time{}
+	s.lock.Unlock()
+}
+
+// ObserveWrite registers that a command was written to the modem.
+func (s *trafficShaper) ObserveWrite() {
+	now := time.Now().UTC()
+
+	s.lock.Lock()
+	s.extendWrite(now.Add(modemWriteDelay))
+	s.lock.Unlock()
+}
+
+// ObserveSent registers that a message was sent to the powerline, which will
+// keep it busy until its acknowledgment comes back.
+func (s *trafficShaper) ObserveSent(msg *Message) {
+	now := time.Now().UTC()
+
+	s.lock.Lock()
+	s.stats.MessagesSent++
+	s.extendWrite(now.Add(messageRoundtripDuration(msg.IsExtended(), msg.MaxHops)))
+	s.lock.Unlock()
+}
+
+// ObserveNak registers that the modem refused to send a message.
+func (s *trafficShaper) ObserveNak() {
+	s.lock.Lock()
+	s.stats.ModemNaks++
+	s.lock.Unlock()
+}
+
+// Wait until the modem is ready for a new command and our own last message
+// got its acknowledgment back.
+//
+// If powerline is set, also wait until the powerline is believed to be free
+// from the traffic of other devices.
+func (s *trafficShaper) Wait(ctx context.Context, powerline bool) error {
+	now := time.Now().UTC()
+
+	s.lock.Lock()
+	until := s.writeUntil
+
+	if powerline {
+		lineUntil := s.busyUntil
+
+		if s.cleanupUntil.After(lineUntil) {
+			lineUntil = s.cleanupUntil
+		}
+
+		if earliest := maxTime(until, now); lineUntil.After(earliest) {
+			s.stats.DelayedWrites++
+			s.stats.TotalDelay += lineUntil.Sub(earliest)
+			until = lineUntil
+		}
+	}
+	s.lock.Unlock()
+
+	delay := until.Sub(now)
+
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Statistics returns a copy of the current statistics.
+func (s *trafficShaper) Statistics() TrafficStatistics {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.stats
+}
+
+func (s *trafficShaper) extendBusy(t time.Time) {
+	if t.After(s.busyUntil) {
+		s.busyUntil = t
+	}
+}
+
+func (s *trafficShaper) extendWrite(t time.Time) {
+	if t.After(s.writeUntil) {
+		s.writeUntil = t
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+func (s *trafficShaper) extendCleanup(t time.Time) {
+	if t.After(s.cleanupUntil) {
+		s.cleanupUntil = t
+	}
+}
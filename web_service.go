@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"mime"
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +23,10 @@ type WebService struct {
 	PowerLineModem PowerLineModem
 	Configuration  *Configuration
 
+	// Devices gives typed access to the devices. If not set, it is built
+	// from the PowerLine Modem and the configuration.
+	Devices *Devices
+
 	// DisablePowerLineModem is a boolean value that, if set, disables
 	// exposition of the PowerLineModem routes.
 	DisablePowerLineModem bool
@@ -39,7 +45,7 @@ type WebService struct {
 	controllers            map[ID]bool
 	responders             map[ID]bool
 	deviceToMasterDevice   map[ID]ID
-	deviceStates           map[ID]*LightState
+	deviceStates           map[ID]interface{}
 	deviceStatesTimestamps map[ID]time.Time
 }
 
@@ -144,38 +150,96 @@ func (s *WebService) Run(ctx context.Context) error {
 			s.lock.Unlock()
 
 			if s.Configuration.Hubitat.HubURL != "" {
-				device, err := s.Configuration.GetDevice(id)
+				// The outlets of an OutletLinc are distinct devices that
+				// share an id.
+				for _, device := range s.Configuration.GetDevices(id) {
+					go s.sendHubitatEvent(ctx, device, event)
+				}
+			}
+		}
+	}()
+
+	return s.Devices.Monitor(ctx, events)
+}
 
-				if err == nil {
-					go func(device *ConfigurationDevice) {
-						ctx, cancel := context.WithTimeout(ctx, time.Second*5)
-						defer cancel()
+// sendHubitatEvent sends the state of a device to Hubitat after an event.
+//
+// Events that raise alarms are sent with a high priority and up to three
+// attempts, with a doubling delay between them.
+func (s *WebService) sendHubitatEvent(ctx context.Context, device ConfigurationDevice, event DeviceEvent) {
+	attempts := 1
+	timeout := time.Second * 5
+
+	if len(event.Raised) > 0 {
+		attempts = 3
+		timeout = time.Second * 20
+	}
 
-						state, err := s.getDeviceState(ctx, device.ID)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-						if err != nil {
-							return
-						}
+	state, err := s.getConfigurationDeviceState(ctx, &device)
 
-						body := &bytes.Buffer{}
-						json.NewEncoder(body).Encode(HubitatEvent{
-							Alias: device.Alias,
-							State: *state,
-						})
+	if err != nil {
+		return
+	}
 
-						req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/event", s.Configuration.Hubitat.HubURL), body)
-						req = req.WithContext(ctx)
+	hubitatEvent := HubitatEvent{
+		Alias: device.Alias,
+		State: state,
+	}
 
-						http.DefaultClient.Do(req)
+	// Remote button actions are sent with the alias of their button, so that
+	// automations can use them.
+	if group, ok := event.Attributes["button"].(int); ok {
+		if button, err := device.LookupButton(fmt.Sprintf("%d", group)); err == nil {
+			hubitatEvent.Button = button.Alias
+			hubitatEvent.Action = event.Type
+		}
+	}
 
-						fmt.Fprintf(os.Stdout, "Sending Hubitat event for device %s.\n", device.Alias)
-					}(device)
-				}
+	if len(event.Raised) > 0 {
+		hubitatEvent.Priority = HubitatPriorityHigh
+	}
+
+	body, err := json.Marshal(hubitatEvent)
+
+	if err != nil {
+		return
+	}
+
+	delay := time.Second
+
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(delay):
+				delay *= 2
+			case <-ctx.Done():
+				return
 			}
 		}
-	}()
 
-	return s.PowerLineModem.Monitor(ctx, events)
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/event", s.Configuration.Hubitat.HubURL), bytes.NewReader(body))
+
+		if err != nil {
+			return
+		}
+
+		req = req.WithContext(ctx)
+
+		fmt.Fprintf(os.Stdout, "Sending Hubitat event for device %s.\n", device.Alias)
+
+		resp, err := http.DefaultClient.Do(req)
+
+		if err == nil {
+			resp.Body.Close()
+
+			if resp.StatusCode < 300 {
+				return
+			}
+		}
+	}
 }
 
 func (s *WebService) init() {
@@ -192,9 +256,19 @@ func (s *WebService) init() {
 			s.ForceRefreshPeriod = 5 * time.Minute
 		}
 
+		// The web-service caches states on its own, for the devices that
+		// are controllers.
+		if s.Devices == nil {
+			s.Devices = &Devices{
+				PowerLineModem: s.PowerLineModem,
+				Configuration:  s.Configuration,
+				CacheDuration:  -1,
+			}
+		}
+
 		s.handler = s.makeHandler()
 		s.deviceToMasterDevice = map[ID]ID{}
-		s.deviceStates = map[ID]*LightState{}
+		s.deviceStates = map[ID]interface{}{}
 		s.deviceStatesTimestamps = map[ID]time.Time{}
 
 		for _, device := range s.Configuration.Devices {
@@ -218,11 +292,16 @@ func (s *WebService) makeHandler() http.Handler {
 	if !s.DisablePowerLineModem {
 		router.Path("/plm/im-info").Methods(http.MethodGet).HandlerFunc(s.handleGetIMInfo)
 		router.Path("/plm/all-link-db").Methods(http.MethodGet).HandlerFunc(s.handleGetAllLinkDB)
+		router.Path("/plm/traffic").Methods(http.MethodGet).HandlerFunc(s.handleGetTrafficStatistics)
+		router.Path("/plm/monitor").Methods(http.MethodGet).HandlerFunc(s.handleMonitor)
 		router.Path("/plm/device/{id}/state").Methods(http.MethodGet).HandlerFunc(s.handleGetDeviceState)
 		router.Path("/plm/device/{id}/state").Methods(http.MethodPut).HandlerFunc(s.handleSetDeviceState)
 		router.Path("/plm/device/{id}/info").Methods(http.MethodGet).HandlerFunc(s.handleGetDeviceInfo)
 		router.Path("/plm/device/{id}/info").Methods(http.MethodPut).HandlerFunc(s.handleSetDeviceInfo)
 		router.Path("/plm/device/{id}/beep").Methods(http.MethodPost).HandlerFunc(s.handleBeep)
+		router.Path("/plm/device/{id}/ping").Methods(http.MethodPost).HandlerFunc(s.handlePing)
+		router.Path("/plm/device/{id}/category").Methods(http.MethodGet).HandlerFunc(s.handleGetDeviceCategory)
+		router.Path("/plm/device/{id}/command").Methods(http.MethodPost).HandlerFunc(s.handleSendCommand)
 	}
 
 	// API routes.
@@ -232,6 +311,28 @@ func (s *WebService) makeHandler() http.Handler {
 		router.Path("/api/device/{device}/state").Methods(http.MethodPut).HandlerFunc(s.handleAPISetDeviceState)
 		router.Path("/api/device/{device}/info").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceInfo)
 		router.Path("/api/device/{device}/info").Methods(http.MethodPut).HandlerFunc(s.handleAPISetDeviceInfo)
+		router.Path("/api/device/{device}/diagnostics").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceDiagnostics)
+		router.Path("/api/device/{device}/buttons").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceButtons)
+		router.Path("/api/device/{device}/button/{button}").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceButton)
+		router.Path("/api/device/{device}/button/{button}").Methods(http.MethodPut).HandlerFunc(s.handleAPISetDeviceButton)
+		router.Path("/api/device/{device}/fan").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceFan)
+		router.Path("/api/device/{device}/fan").Methods(http.MethodPut).HandlerFunc(s.handleAPISetDeviceFan)
+		router.Path("/api/device/{device}/thermostat").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceThermostat)
+		router.Path("/api/device/{device}/thermostat").Methods(http.MethodPut).HandlerFunc(s.handleAPISetDeviceThermostat)
+		router.Path("/api/device/{device}/thermostat/clock").Methods(http.MethodPost).HandlerFunc(s.handleAPISyncDeviceThermostatClock)
+		router.Path("/api/device/{device}/garage-door").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceGarageDoor)
+		router.Path("/api/device/{device}/garage-door").Methods(http.MethodPut).HandlerFunc(s.handleAPISetDeviceGarageDoor)
+		router.Path("/api/device/{device}/energy").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceEnergy)
+		router.Path("/api/device/{device}/energy/reset").Methods(http.MethodPost).HandlerFunc(s.handleAPIResetDeviceEnergy)
+		router.Path("/api/device/{device}/alarms").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceAlarms)
+		router.Path("/api/device/{device}/alarms/acknowledge").Methods(http.MethodPost).HandlerFunc(s.handleAPIAcknowledgeDeviceAlarms)
+		router.Path("/api/device/{device}/alarms/clear").Methods(http.MethodPost).HandlerFunc(s.handleAPIClearDeviceAlarms)
+		router.Path("/api/device/{device}/queue").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDeviceQueue)
+		router.Path("/api/device/{device}/queue").Methods(http.MethodPost).HandlerFunc(s.handleAPIEnqueueDeviceCommand)
+		router.Path("/api/device/{device}/command/{command}").Methods(http.MethodPost).HandlerFunc(s.handleAPIRunDeviceCommand)
+		router.Path("/api/diagnostics").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDiagnostics)
+		router.Path("/api/drivers").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetDrivers)
+		router.Path("/api/alarms").Methods(http.MethodGet).HandlerFunc(s.handleAPIGetAlarms)
 	}
 
 	if !WebServiceDebug {
@@ -309,6 +410,63 @@ func (s *WebService) handleBeep(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *WebService) handlePing(w http.ResponseWriter, r *http.Request) {
+	id := s.parseID(w, r)
+
+	if id == nil {
+		return
+	}
+
+	result, err := s.PowerLineModem.Ping(r.Context(), *id)
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, result)
+}
+
+func (s *WebService) handleGetDeviceCategory(w http.ResponseWriter, r *http.Request) {
+	id := s.parseID(w, r)
+
+	if id == nil {
+		return
+	}
+
+	category, err := s.PowerLineModem.GetDeviceCategory(r.Context(), *id)
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, category)
+}
+
+func (s *WebService) handleSendCommand(w http.ResponseWriter, r *http.Request) {
+	id := s.parseID(w, r)
+
+	if id == nil {
+		return
+	}
+
+	command := &Command{}
+
+	if !s.decodeValue(w, r, command) {
+		return
+	}
+
+	response, err := s.PowerLineModem.SendCommand(r.Context(), *id, *command)
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, response)
+}
+
 func (s *WebService) handleGetDeviceInfo(w http.ResponseWriter, r *http.Request) {
 	id := s.parseID(w, r)
 
@@ -358,11 +516,63 @@ func (s *WebService) handleGetAllLinkDB(w http.ResponseWriter, r *http.Request)
 	s.handleValue(w, r, records)
 }
 
+func (s *WebService) handleMonitor(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events := make(chan DeviceEvent, 10)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- s.PowerLineModem.Monitor(ctx, events)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case event := <-events:
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-errs:
+			return
+		}
+	}
+}
+
+func (s *WebService) handleGetTrafficStatistics(w http.ResponseWriter, r *http.Request) {
+	plm, ok := s.PowerLineModem.(interface {
+		TrafficStatistics() TrafficStatistics
+	})
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "traffic statistics are not available for this PowerLine Modem")
+
+		return
+	}
+
+	s.handleValue(w, r, plm.TrafficStatistics())
+}
+
 func (s *WebService) handleAPIGetDevices(w http.ResponseWriter, r *http.Request) {
 	s.handleValue(w, r, s.Configuration.Devices)
 }
 
-func (s *WebService) getDeviceState(ctx context.Context, id ID) (*LightState, error) {
+func (s *WebService) getDeviceState(ctx context.Context, id ID) (interface{}, error) {
 	now := time.Now().UTC()
 
 	s.lock.Lock()
@@ -381,7 +591,7 @@ func (s *WebService) getDeviceState(ctx context.Context, id ID) (*LightState, er
 
 	if state == nil {
 		var err error
-		state, err = s.PowerLineModem.GetDeviceState(ctx, id)
+		state, err = s.Devices.GetState(ctx, id)
 
 		if err != nil {
 			return nil, err
@@ -407,7 +617,7 @@ func (s *WebService) handleAPIGetDeviceState(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	state, err := s.getDeviceState(r.Context(), device.ID)
+	state, err := s.getConfigurationDeviceState(r.Context(), device)
 
 	if err != nil {
 		s.handleError(w, r, err)
@@ -417,10 +627,53 @@ func (s *WebService) handleAPIGetDeviceState(w http.ResponseWriter, r *http.Requ
 	s.handleValue(w, r, state)
 }
 
-func (s *WebService) handleAPISetDeviceState(w http.ResponseWriter, r *http.Request) {
-	device := s.parseDevice(w, r)
+// getConfigurationDeviceState returns the state of a configured device,
+// which is a light state for the outlets of OutletLincs.
+func (s *WebService) getConfigurationDeviceState(ctx context.Context, device *ConfigurationDevice) (interface{}, error) {
+	if device.Outlet == 0 {
+		return s.getDeviceState(ctx, device.ID)
+	}
 
-	if device == nil {
+	outletLinc, err := s.getOutletLinc(ctx, device)
+
+	if err != nil {
+		return nil, err
+	}
+
+	on, err := outletLinc.IsOutletOn(ctx, device.Outlet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if on {
+		return &LightState{OnOff: LightOn, Level: 1}, nil
+	}
+
+	return &LightState{OnOff: LightOff}, nil
+}
+
+func (s *WebService) getOutletLinc(ctx context.Context, device *ConfigurationDevice) (OutletLinc, error) {
+	handle, err := s.Devices.Device(ctx, device.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	outletLinc, ok := handle.(OutletLinc)
+
+	if !ok {
+		return nil, fmt.Errorf("device %s (%s) is not an outlet linc", device.Name, device.ID)
+	}
+
+	return outletLinc, nil
+}
+
+func (s *WebService) handleAPISetOutletState(w http.ResponseWriter, r *http.Request, device *ConfigurationDevice) {
+	outletLinc, err := s.getOutletLinc(r.Context(), device)
+
+	if err != nil {
+		s.handleError(w, r, err)
 		return
 	}
 
@@ -437,15 +690,53 @@ func (s *WebService) handleAPISetDeviceState(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := s.PowerLineModem.SetDeviceState(r.Context(), device.ID, *state); err != nil {
+	if err := outletLinc.SetOutlet(r.Context(), device.Outlet, state.OnOff == LightOn); err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	for _, id := range device.MirrorDeviceIDs {
-		if s.responders == nil || s.responders[id] {
-			s.PowerLineModem.SetDeviceState(r.Context(), id, *state)
-		}
+	// The outlet was set: failing the request would have it retried.
+	if err := s.setMirrors(r.Context(), device, state, DeviceDriver.NewState, DeviceDriver.SetState); err != nil {
+		s.warn(w, err)
+	}
+
+	s.handleValue(w, r, state)
+}
+
+func (s *WebService) handleAPISetDeviceState(w http.ResponseWriter, r *http.Request) {
+	device := s.parseDevice(w, r)
+
+	if device == nil {
+		return
+	}
+
+	if device.Outlet != 0 {
+		s.handleAPISetOutletState(w, r, device)
+		return
+	}
+
+	handle := s.Devices.deviceOrDefault(r.Context(), device.ID)
+	state := handle.Driver().NewState()
+
+	if state == nil {
+		s.handleError(w, r, ErrNotSupported)
+		return
+	}
+
+	if !s.decodeValue(w, r, state) {
+		return
+	}
+
+	// If the device is not a responder, don't bother sending a command to it.
+	if s.responders != nil && !s.responders[device.ID] {
+		err := fmt.Errorf("device %s (%s) is registered as a responder", device.Name, device.ID)
+		s.handleError(w, r, err)
+		return
+	}
+
+	if err := handle.Driver().SetState(r.Context(), handle, state); err != nil {
+		s.handleError(w, r, err)
+		return
 	}
 
 	// Only cache the state if the device is a controller, otherwise it
@@ -457,6 +748,11 @@ func (s *WebService) handleAPISetDeviceState(w http.ResponseWriter, r *http.Requ
 		s.lock.Unlock()
 	}
 
+	// The device was set: failing the request would have it retried.
+	if err := s.setMirrors(r.Context(), device, state, DeviceDriver.NewState, DeviceDriver.SetState); err != nil {
+		s.warn(w, err)
+	}
+
 	s.handleValue(w, r, state)
 }
 
@@ -467,7 +763,7 @@ func (s *WebService) handleAPIGetDeviceInfo(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	deviceInfo, err := s.PowerLineModem.GetDeviceInfo(r.Context(), device.ID)
+	deviceInfo, err := s.Devices.GetInfo(r.Context(), device.ID)
 
 	if err != nil {
 		s.handleError(w, r, err)
@@ -484,101 +780,797 @@ func (s *WebService) handleAPISetDeviceInfo(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	deviceInfo := &DeviceInfo{}
+	handle := s.Devices.deviceOrDefault(r.Context(), device.ID)
+	deviceInfo := handle.Driver().NewInfo()
+
+	if deviceInfo == nil {
+		s.handleError(w, r, ErrNotSupported)
+		return
+	}
 
 	if !s.decodeValue(w, r, deviceInfo) {
 		return
 	}
 
-	if err := s.PowerLineModem.SetDeviceInfo(r.Context(), device.ID, *deviceInfo); err != nil {
+	if err := handle.Driver().SetInfo(r.Context(), handle, deviceInfo); err != nil {
 		s.handleError(w, r, err)
 		return
 	}
 
-	for _, id := range device.MirrorDeviceIDs {
-		s.PowerLineModem.SetDeviceInfo(r.Context(), id, *deviceInfo)
+	// The device was set: failing the request would have it retried.
+	if err := s.setMirrors(r.Context(), device, deviceInfo, DeviceDriver.NewInfo, DeviceDriver.SetInfo); err != nil {
+		s.warn(w, err)
 	}
 
 	s.handleValue(w, r, deviceInfo)
 }
 
-func (s *WebService) parseID(w http.ResponseWriter, r *http.Request) *ID {
-	vars := mux.Vars(r)
+// warn reports, in a Warning header, a failure that doesn't fail the
+// request.
+func (s *WebService) warn(w http.ResponseWriter, err error) {
+	fmt.Fprintf(os.Stderr, "%s.\n", err)
+	w.Header().Add("Warning", fmt.Sprintf("199 - %q", err.Error()))
+}
 
-	idStr := vars["id"]
+// setMirrors applies a state, or information, set on a device to its
+// mirrors.
+//
+// The value is converted to the type expected by the driver of each mirror,
+// which may differ from the one of the device.
+func (s *WebService) setMirrors(ctx context.Context, device *ConfigurationDevice, value interface{}, newValue func(DeviceDriver) interface{}, set func(DeviceDriver, context.Context, Device, interface{}) error) error {
+	data, err := json.Marshal(value)
 
-	if idStr == "" {
-		err := fmt.Errorf("invalid empty device id")
+	if err != nil {
+		return fmt.Errorf("marshalling value: %s", err)
+	}
 
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(w, "%s", err)
+	var failures []string
 
-		return nil
-	}
+	for _, id := range device.MirrorDeviceIDs {
+		if s.responders != nil && !s.responders[id] {
+			continue
+		}
 
-	id, err := ParseID(idStr)
+		mirror := s.Devices.deviceOrDefault(ctx, id)
+		mirrorValue := newValue(mirror.Driver())
 
-	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(w, "%s", err)
+		if mirrorValue == nil {
+			err = ErrNotSupported
+		} else if err = json.Unmarshal(data, mirrorValue); err == nil {
+			err = set(mirror.Driver(), ctx, mirror, mirrorValue)
+		}
 
-		return nil
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("mirror device %s: %s", id, err))
+		}
 	}
 
-	return &id
-}
+	if len(failures) > 0 {
+		return fmt.Errorf("device %s (%s) was set but not all its mirrors: %s", device.Name, device.ID, strings.Join(failures, ", "))
+	}
 
-func (s *WebService) parseDevice(w http.ResponseWriter, r *http.Request) *ConfigurationDevice {
-	vars := mux.Vars(r)
+	return nil
+}
 
-	id := vars["device"]
+func (s *WebService) handleAPIGetDeviceButtons(w http.ResponseWriter, r *http.Request) {
+	device, keypad := s.parseKeypad(w, r)
 
-	if id == "" {
-		err := fmt.Errorf("invalid empty device identifier")
+	if keypad == nil {
+		return
+	}
 
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(w, "%s", err)
+	mask, err := keypad.GetLEDMask(r.Context())
 
-		return nil
+	if err != nil {
+		s.handleError(w, r, err)
+		return
 	}
 
-	device, err := s.Configuration.LookupDevice(id)
+	groups := keypad.ButtonGroups()
+	buttons := make([]KeypadButtonState, len(groups))
 
-	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintf(w, "%s", err)
+	for i, group := range groups {
+		led := mask&buttonBit(group) != 0
+		buttons[i] = KeypadButtonState{
+			Group: group,
+			LED:   &led,
+		}
 
-		return nil
+		if button, err := device.LookupButton(fmt.Sprintf("%d", group)); err == nil {
+			buttons[i].Name = button.Name
+			buttons[i].Alias = button.Alias
+		}
 	}
 
-	return device
+	s.handleValue(w, r, buttons)
 }
 
-func (s *WebService) decodeValue(w http.ResponseWriter, r *http.Request, value interface{}) bool {
-	if r.Body == nil {
-		err := fmt.Errorf("missing body")
+func (s *WebService) handleAPIGetDeviceButton(w http.ResponseWriter, r *http.Request) {
+	device, keypad := s.parseKeypad(w, r)
 
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "%s", err)
+	if keypad == nil {
+		return
+	}
 
-		return false
+	button := s.parseButton(w, r, device)
+
+	if button == nil {
+		return
 	}
 
-	mediatype, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	mask, err := keypad.GetLEDMask(r.Context())
 
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "%s", err)
-
-		return false
+		s.handleError(w, r, err)
+		return
 	}
 
-	switch mediatype {
-	case "":
-		mediatype = "application/json"
-	case "application/json":
-	default:
-		err := fmt.Errorf("expected body of type application/json")
+	info, err := keypad.GetButtonInfo(r.Context(), button.Group)
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	led := mask&buttonBit(button.Group) != 0
+
+	s.handleValue(w, r, KeypadButtonState{
+		Group: button.Group,
+		Name:  button.Name,
+		Alias: button.Alias,
+		LED:   &led,
+		Info:  info,
+	})
+}
+
+func (s *WebService) handleAPISetDeviceButton(w http.ResponseWriter, r *http.Request) {
+	device, keypad := s.parseKeypad(w, r)
+
+	if keypad == nil {
+		return
+	}
+
+	button := s.parseButton(w, r, device)
+
+	if button == nil {
+		return
+	}
+
+	state := &KeypadButtonState{}
+
+	if !s.decodeValue(w, r, state) {
+		return
+	}
+
+	if state.LED != nil {
+		mask, err := keypad.GetLEDMask(r.Context())
+
+		if err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+
+		if *state.LED {
+			mask |= buttonBit(button.Group)
+		} else {
+			mask &^= buttonBit(button.Group)
+		}
+
+		if err := keypad.SetLEDMask(r.Context(), mask); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	if state.Info != nil {
+		if err := keypad.SetButtonInfo(r.Context(), button.Group, *state.Info); err != nil {
+			s.handleError(w, r, err)
+			return
+		}
+	}
+
+	state.Group = button.Group
+	state.Name = button.Name
+	state.Alias = button.Alias
+
+	s.handleValue(w, r, state)
+}
+
+func (s *WebService) handleAPIGetDeviceFan(w http.ResponseWriter, r *http.Request) {
+	fan := s.parseFan(w, r)
+
+	if fan == nil {
+		return
+	}
+
+	speed, err := fan.GetSpeed(r.Context())
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, FanState{Speed: speed})
+}
+
+func (s *WebService) handleAPISetDeviceFan(w http.ResponseWriter, r *http.Request) {
+	fan := s.parseFan(w, r)
+
+	if fan == nil {
+		return
+	}
+
+	state := &FanState{}
+
+	if !s.decodeValue(w, r, state) {
+		return
+	}
+
+	if err := fan.SetSpeed(r.Context(), state.Speed); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, state)
+}
+
+func (s *WebService) handleAPIGetDeviceThermostat(w http.ResponseWriter, r *http.Request) {
+	thermostat := s.parseThermostat(w, r)
+
+	if thermostat == nil {
+		return
+	}
+
+	state, err := thermostat.GetStatus(r.Context())
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, state)
+}
+
+func (s *WebService) handleAPISetDeviceThermostat(w http.ResponseWriter, r *http.Request) {
+	thermostat := s.parseThermostat(w, r)
+
+	if thermostat == nil {
+		return
+	}
+
+	state := &ThermostatSettings{}
+
+	if !s.decodeValue(w, r, state) {
+		return
+	}
+
+	if err := thermostat.Driver().SetState(r.Context(), thermostat, state); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, state)
+}
+
+func (s *WebService) handleAPISyncDeviceThermostatClock(w http.ResponseWriter, r *http.Request) {
+	thermostat := s.parseThermostat(w, r)
+
+	if thermostat == nil {
+		return
+	}
+
+	if err := thermostat.SyncClock(r.Context(), time.Now()); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *WebService) handleAPIGetDeviceEnergy(w http.ResponseWriter, r *http.Request) {
+	energyMeter := s.parseEnergyMeter(w, r)
+
+	if energyMeter == nil {
+		return
+	}
+
+	reading, err := energyMeter.GetEnergy(r.Context())
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, reading)
+}
+
+func (s *WebService) handleAPIResetDeviceEnergy(w http.ResponseWriter, r *http.Request) {
+	energyMeter := s.parseEnergyMeter(w, r)
+
+	if energyMeter == nil {
+		return
+	}
+
+	if err := energyMeter.ResetEnergy(r.Context()); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+}
+
+func (s *WebService) handleAPIGetAlarms(w http.ResponseWriter, r *http.Request) {
+	alarms := map[string][]Alarm{}
+
+	for id, deviceAlarms := range s.Devices.Alarms() {
+		devices := s.Configuration.GetDevices(id)
+
+		if len(devices) == 0 {
+			alarms[id.String()] = deviceAlarms
+			continue
+		}
+
+		for _, device := range devices {
+			alarms[device.Alias] = deviceAlarms
+		}
+	}
+
+	s.handleValue(w, r, alarms)
+}
+
+func (s *WebService) handleAPIGetDeviceAlarms(w http.ResponseWriter, r *http.Request) {
+	sensor := s.parseSensor(w, r)
+
+	if sensor == nil {
+		return
+	}
+
+	s.handleValue(w, r, sensor.Alarms())
+}
+
+func (s *WebService) handleAPIAcknowledgeDeviceAlarms(w http.ResponseWriter, r *http.Request) {
+	sensor := s.parseSensor(w, r)
+
+	if sensor == nil {
+		return
+	}
+
+	sensor.AcknowledgeAlarms()
+
+	s.handleValue(w, r, sensor.Alarms())
+}
+
+// handleAPIClearDeviceAlarms clears the alarms whose conditions ended and
+// returns the ones that are still active.
+func (s *WebService) handleAPIClearDeviceAlarms(w http.ResponseWriter, r *http.Request) {
+	sensor := s.parseSensor(w, r)
+
+	if sensor == nil {
+		return
+	}
+
+	s.handleValue(w, r, sensor.ClearAlarms())
+}
+
+func (s *WebService) handleAPIGetDeviceGarageDoor(w http.ResponseWriter, r *http.Request) {
+	door := s.parseGarageDoor(w, r)
+
+	if door == nil {
+		return
+	}
+
+	state, err := door.GetDoorState(r.Context())
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, GarageDoorState{State: state})
+}
+
+// handleAPISetDeviceGarageDoor opens or closes a garage door, depending on
+// whether the requested state is open or closed.
+func (s *WebService) handleAPISetDeviceGarageDoor(w http.ResponseWriter, r *http.Request) {
+	door := s.parseGarageDoor(w, r)
+
+	if door == nil {
+		return
+	}
+
+	state := &GarageDoorState{}
+
+	if !s.decodeValue(w, r, state) {
+		return
+	}
+
+	var err error
+
+	switch state.State {
+	case DoorOpen:
+		err = door.OpenDoor(r.Context())
+	case DoorClosed:
+		err = door.CloseDoor(r.Context())
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "expected a state of open or closed but got: %s", state.State)
+
+		return
+	}
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	if state.State, err = door.GetDoorState(r.Context()); err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, state)
+}
+
+func (s *WebService) handleAPIGetDeviceQueue(w http.ResponseWriter, r *http.Request) {
+	sensor := s.parseSensor(w, r)
+
+	if sensor == nil {
+		return
+	}
+
+	s.handleValue(w, r, sensor.PendingCommands())
+}
+
+func (s *WebService) handleAPIEnqueueDeviceCommand(w http.ResponseWriter, r *http.Request) {
+	sensor := s.parseSensor(w, r)
+
+	if sensor == nil {
+		return
+	}
+
+	command := &Command{}
+
+	if !s.decodeValue(w, r, command) {
+		return
+	}
+
+	name := fmt.Sprintf("send %02x", command.CommandBytes)
+
+	sensor.Enqueue(name, func(ctx context.Context, plm PowerLineModem) error {
+		_, err := plm.SendCommand(ctx, sensor.ID(), *command)
+
+		return err
+	})
+
+	s.handleValue(w, r, sensor.PendingCommands())
+}
+
+func (s *WebService) handleAPIRunDeviceCommand(w http.ResponseWriter, r *http.Request) {
+	device := s.parseDevice(w, r)
+
+	if device == nil {
+		return
+	}
+
+	var args []string
+
+	if r.ContentLength != 0 {
+		if !s.decodeValue(w, r, &args) {
+			return
+		}
+	}
+
+	result, err := s.Devices.RunCommand(r.Context(), device.ID, mux.Vars(r)["command"], args)
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return
+	}
+
+	s.handleValue(w, r, result)
+}
+
+func (s *WebService) handleAPIGetDrivers(w http.ResponseWriter, r *http.Request) {
+	drivers := DeviceDrivers()
+	descriptions := make([]DeviceDriverDescription, len(drivers))
+
+	for i, driver := range drivers {
+		descriptions[i] = DescribeDeviceDriver(driver)
+	}
+
+	s.handleValue(w, r, descriptions)
+}
+
+func (s *WebService) handleAPIGetDeviceDiagnostics(w http.ResponseWriter, r *http.Request) {
+	device := s.parseDevice(w, r)
+
+	if device == nil {
+		return
+	}
+
+	count, interval, ok := s.parseDiagnosticsParameters(w, r)
+
+	if !ok {
+		return
+	}
+
+	s.handleValue(w, r, Diagnose(r.Context(), s.PowerLineModem, device.ID, count, interval, nil))
+}
+
+func (s *WebService) handleAPIGetDiagnostics(w http.ResponseWriter, r *http.Request) {
+	count, interval, ok := s.parseDiagnosticsParameters(w, r)
+
+	if !ok {
+		return
+	}
+
+	ids := make([]ID, len(s.Configuration.Devices))
+
+	for i, device := range s.Configuration.Devices {
+		ids[i] = device.ID
+	}
+
+	s.handleValue(w, r, DiagnoseAll(r.Context(), s.PowerLineModem, ids, count, interval))
+}
+
+// The limits of the diagnostics parameters, so that a single request can't
+// hold the PowerLine Modem for too long.
+const (
+	maxDiagnosticsCount    = 100
+	maxDiagnosticsInterval = time.Second * 10
+)
+
+func (s *WebService) parseDiagnosticsParameters(w http.ResponseWriter, r *http.Request) (count int, interval time.Duration, ok bool) {
+	count = 5
+	interval = time.Millisecond * 500
+
+	var err error
+
+	if value := r.URL.Query().Get("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil || count <= 0 || count > maxDiagnosticsCount {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "invalid count, expected 1 to %d: %s", maxDiagnosticsCount, value)
+
+			return
+		}
+	}
+
+	if value := r.URL.Query().Get("interval"); value != "" {
+		if interval, err = time.ParseDuration(value); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "%s", err)
+
+			return
+		}
+
+		if interval < 0 || interval > maxDiagnosticsInterval {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "invalid interval, expected at most %s: %s", maxDiagnosticsInterval, value)
+
+			return
+		}
+	}
+
+	return count, interval, true
+}
+
+func (s *WebService) parseID(w http.ResponseWriter, r *http.Request) *ID {
+	vars := mux.Vars(r)
+
+	idStr := vars["id"]
+
+	if idStr == "" {
+		err := fmt.Errorf("invalid empty device id")
+
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%s", err)
+
+		return nil
+	}
+
+	id, err := ParseID(idStr)
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%s", err)
+
+		return nil
+	}
+
+	return &id
+}
+
+func (s *WebService) parseDevice(w http.ResponseWriter, r *http.Request) *ConfigurationDevice {
+	vars := mux.Vars(r)
+
+	id := vars["device"]
+
+	if id == "" {
+		err := fmt.Errorf("invalid empty device identifier")
+
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%s", err)
+
+		return nil
+	}
+
+	device, err := s.Configuration.LookupDevice(id)
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%s", err)
+
+		return nil
+	}
+
+	return device
+}
+
+func (s *WebService) parseHandle(w http.ResponseWriter, r *http.Request) (*ConfigurationDevice, Device) {
+	device := s.parseDevice(w, r)
+
+	if device == nil {
+		return nil, nil
+	}
+
+	handle, err := s.Devices.Device(r.Context(), device.ID)
+
+	if err != nil {
+		s.handleError(w, r, err)
+		return nil, nil
+	}
+
+	return device, handle
+}
+
+func (s *WebService) parseKeypad(w http.ResponseWriter, r *http.Request) (*ConfigurationDevice, Keypad) {
+	device, handle := s.parseHandle(w, r)
+
+	if handle == nil {
+		return nil, nil
+	}
+
+	keypad, ok := handle.(Keypad)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "device %s (%s) is not a keypad", device.Name, device.ID)
+
+		return nil, nil
+	}
+
+	return device, keypad
+}
+
+func (s *WebService) parseFan(w http.ResponseWriter, r *http.Request) Fan {
+	device, handle := s.parseHandle(w, r)
+
+	if handle == nil {
+		return nil
+	}
+
+	fan, ok := handle.(Fan)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "device %s (%s) is not a fan", device.Name, device.ID)
+
+		return nil
+	}
+
+	return fan
+}
+
+func (s *WebService) parseThermostat(w http.ResponseWriter, r *http.Request) Thermostat {
+	device, handle := s.parseHandle(w, r)
+
+	if handle == nil {
+		return nil
+	}
+
+	thermostat, ok := handle.(Thermostat)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "device %s (%s) is not a thermostat", device.Name, device.ID)
+
+		return nil
+	}
+
+	return thermostat
+}
+
+func (s *WebService) parseGarageDoor(w http.ResponseWriter, r *http.Request) GarageDoor {
+	device, handle := s.parseHandle(w, r)
+
+	if handle == nil {
+		return nil
+	}
+
+	door, ok := handle.(GarageDoor)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "device %s (%s) is not a garage door", device.Name, device.ID)
+
+		return nil
+	}
+
+	return door
+}
+
+func (s *WebService) parseEnergyMeter(w http.ResponseWriter, r *http.Request) EnergyMeter {
+	device, handle := s.parseHandle(w, r)
+
+	if handle == nil {
+		return nil
+	}
+
+	energyMeter, ok := handle.(EnergyMeter)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "device %s (%s) is not an energy meter", device.Name, device.ID)
+
+		return nil
+	}
+
+	return energyMeter
+}
+
+func (s *WebService) parseSensor(w http.ResponseWriter, r *http.Request) Sensor {
+	device, handle := s.parseHandle(w, r)
+
+	if handle == nil {
+		return nil
+	}
+
+	sensor, ok := handle.(Sensor)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "device %s (%s) is not a sensor", device.Name, device.ID)
+
+		return nil
+	}
+
+	return sensor
+}
+
+func (s *WebService) parseButton(w http.ResponseWriter, r *http.Request, device *ConfigurationDevice) *ConfigurationButton {
+	button, err := device.LookupButton(mux.Vars(r)["button"])
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "%s", err)
+
+		return nil
+	}
+
+	return button
+}
+
+func (s *WebService) decodeValue(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	if r.Body == nil {
+		err := fmt.Errorf("missing body")
+
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%s", err)
+
+		return false
+	}
+
+	mediatype, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "%s", err)
+
+		return false
+	}
+
+	switch mediatype {
+	case "":
+		mediatype = "application/json"
+	case "application/json":
+	default:
+		err := fmt.Errorf("expected body of type application/json")
 
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(w, "%s", err)
@@ -597,7 +1589,17 @@ func (s *WebService) decodeValue(w http.ResponseWriter, r *http.Request, value i
 }
 
 func (s *WebService) handleError(w http.ResponseWriter, r *http.Request, err error) {
-	w.WriteHeader(http.StatusInternalServerError)
+	var noSuchCommand ErrNoSuchCommand
+
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		w.WriteHeader(http.StatusNotImplemented)
+	case errors.As(err, &noSuchCommand):
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
 	fmt.Fprintf(w, "%s", err)
 }
 
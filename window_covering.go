@@ -0,0 +1,306 @@
+package insteon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	commandBytesStopManualChange = [2]byte{0x18, 0x00}
+)
+
+// The extended data sets of window coverings.
+const (
+	windowCoveringDataSetTravelTime byte = 0x1a
+	windowCoveringDataSetDirection  byte = 0x1b
+)
+
+// WindowCovering is a window covering, like a Micro Open/Close module
+// driving shades or blinds.
+//
+// Its position is its level: 0 when closed and 1 when fully open.
+type WindowCovering interface {
+	Device
+	GetPosition(ctx context.Context) (float64, error)
+	SetPosition(ctx context.Context, position float64) error
+	Open(ctx context.Context) error
+	Close(ctx context.Context) error
+	Stop(ctx context.Context) error
+	SetTravelTime(ctx context.Context, travelTime time.Duration) error
+	SetReversed(ctx context.Context, reversed bool) error
+}
+
+// WindowCoveringState represents the state of a window covering.
+type WindowCoveringState struct {
+	Position float64 `json:"position"`
+}
+
+type windowCovering struct {
+	*BaseDevice
+}
+
+// GetPosition returns the position of the window covering.
+func (d *windowCovering) GetPosition(ctx context.Context) (float64, error) {
+	state, err := d.getLightState(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return state.Level, nil
+}
+
+// SetPosition moves the window covering to a position.
+func (d *windowCovering) SetPosition(ctx context.Context, position float64) error {
+	if position <= 0 {
+		return d.Close(ctx)
+	}
+
+	return d.setLightState(ctx, LightState{OnOff: LightOn, Level: position})
+}
+
+// Open fully opens the window covering.
+func (d *windowCovering) Open(ctx context.Context) error {
+	return d.setLightState(ctx, LightState{OnOff: LightOn, Level: 1})
+}
+
+// Close fully closes the window covering.
+func (d *windowCovering) Close(ctx context.Context) error {
+	return d.setLightState(ctx, LightState{OnOff: LightOff})
+}
+
+// Stop stops the window covering where it is.
+func (d *windowCovering) Stop(ctx context.Context) error {
+	_, err := d.SendCommand(ctx, NewCommand(commandBytesStopManualChange))
+
+	return err
+}
+
+// SetTravelTime calibrates the time the motor runs to fully open or close
+// the window covering, from 1 to 255 seconds.
+func (d *windowCovering) SetTravelTime(ctx context.Context, travelTime time.Duration) error {
+	seconds := travelTime / time.Second
+
+	if seconds < 1 {
+		seconds = 1
+	} else if seconds > 0xff {
+		seconds = 0xff
+	}
+
+	_, err := d.SendCommand(ctx, NewExtendedCommand(commandBytesExtendedGetSet, [14]byte{0x01, windowCoveringDataSetTravelTime, byte(seconds)}))
+
+	return err
+}
+
+// SetReversed sets whether the direction of the motor is reversed.
+func (d *windowCovering) SetReversed(ctx context.Context, reversed bool) error {
+	var direction byte
+
+	if reversed {
+		direction = 0x01
+	}
+
+	_, err := d.SendCommand(ctx, NewExtendedCommand(commandBytesExtendedGetSet, [14]byte{0x01, windowCoveringDataSetDirection, direction}))
+
+	return err
+}
+
+// windowCoveringDriver is the driver of window coverings.
+type windowCoveringDriver struct {
+	BaseDeviceDriver
+}
+
+func (windowCoveringDriver) Name() string {
+	return "window-covering"
+}
+
+func (windowCoveringDriver) NewDevice(base *BaseDevice) Device {
+	return &windowCovering{base}
+}
+
+func (windowCoveringDriver) NewState() interface{} {
+	return &WindowCoveringState{}
+}
+
+func (windowCoveringDriver) GetState(ctx context.Context, device Device) (interface{}, error) {
+	windowCovering, ok := device.(WindowCovering)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	position, err := windowCovering.GetPosition(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &WindowCoveringState{Position: position}, nil
+}
+
+func (windowCoveringDriver) SetState(ctx context.Context, device Device, state interface{}) error {
+	windowCovering, ok := device.(WindowCovering)
+
+	if !ok {
+		return ErrNotSupported
+	}
+
+	windowCoveringState, ok := state.(*WindowCoveringState)
+
+	if !ok {
+		return fmt.Errorf("unexpected state type: %T", state)
+	}
+
+	return windowCovering.SetPosition(ctx, windowCoveringState.Position)
+}
+
+func (windowCoveringDriver) Commands() []DeviceCommand {
+	return []DeviceCommand{
+		{
+			Name:        "open",
+			Description: "Fully open the window covering",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				windowCovering, err := asWindowCovering(device, args, 0)
+
+				if err != nil {
+					return nil, err
+				}
+
+				return nil, windowCovering.Open(ctx)
+			},
+		},
+		{
+			Name:        "close",
+			Description: "Fully close the window covering",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				windowCovering, err := asWindowCovering(device, args, 0)
+
+				if err != nil {
+					return nil, err
+				}
+
+				return nil, windowCovering.Close(ctx)
+			},
+		},
+		{
+			Name:        "stop",
+			Description: "Stop the window covering where it is",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				windowCovering, err := asWindowCovering(device, args, 0)
+
+				if err != nil {
+					return nil, err
+				}
+
+				return nil, windowCovering.Stop(ctx)
+			},
+		},
+		{
+			Name:        "position",
+			Usage:       "[<position>]",
+			Description: "Show the position of the window covering, or move it, as a decimal value in the [0, 1] range",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				windowCovering, ok := device.(WindowCovering)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				switch len(args) {
+				case 0:
+					return windowCovering.GetPosition(ctx)
+				case 1:
+					position, err := strconv.ParseFloat(args[0], 64)
+
+					if err != nil {
+						return nil, fmt.Errorf("parsing position: %s", err)
+					}
+
+					return nil, windowCovering.SetPosition(ctx, position)
+				}
+
+				return nil, fmt.Errorf("expected an optional position")
+			},
+		},
+		{
+			Name:        "travel-time",
+			Usage:       "[<duration>]",
+			Description: "Calibrate the travel time of the window covering, which defaults to the configured one",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				windowCovering, ok := device.(*windowCovering)
+
+				if !ok {
+					return nil, ErrNotSupported
+				}
+
+				travelTime := windowCovering.travelTime()
+
+				switch len(args) {
+				case 0:
+				case 1:
+					duration, err := time.ParseDuration(args[0])
+
+					if err != nil {
+						return nil, err
+					}
+
+					travelTime = duration
+				default:
+					return nil, fmt.Errorf("expected an optional duration")
+				}
+
+				return travelTime.String(), windowCovering.SetTravelTime(ctx, travelTime)
+			},
+		},
+		{
+			Name:        "direction",
+			Usage:       "<normal|reversed>",
+			Description: "Set the direction of the motor",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				windowCovering, err := asWindowCovering(device, args, 1)
+
+				if err != nil {
+					return nil, err
+				}
+
+				switch args[0] {
+				case "normal":
+					return nil, windowCovering.SetReversed(ctx, false)
+				case "reversed":
+					return nil, windowCovering.SetReversed(ctx, true)
+				}
+
+				return nil, fmt.Errorf("expected normal or reversed but got: %s", args[0])
+			},
+		},
+		{
+			Name:        "beep",
+			Description: "Make the device beep",
+			Run: func(ctx context.Context, device Device, args []string) (interface{}, error) {
+				return nil, device.Beep(ctx)
+			},
+		},
+	}
+}
+
+// asWindowCovering returns a device as a window covering, after checking
+// the number of arguments of a command.
+func asWindowCovering(device Device, args []string, count int) (WindowCovering, error) {
+	windowCovering, ok := device.(WindowCovering)
+
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if len(args) != count {
+		return nil, fmt.Errorf("expected %d argument(s) but got %d", count, len(args))
+	}
+
+	return windowCovering, nil
+}
+
+func init() {
+	RegisterMainCategoryDeviceDriver(windowCoveringDriver{}, windowCoverings)
+}